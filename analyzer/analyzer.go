@@ -0,0 +1,133 @@
+// Package analyzer provides the license check as a golang.org/x/tools/go/analysis Analyzer, to run it with go vet
+// -vettool or in editors with gopls. The imports of packages with a denied (or incompatible, or not allowed) license
+// are reported at the import statement.
+package analyzer
+
+import (
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer checks the licenses of the imports of a package, with the configuration file of its module
+var Analyzer = &analysis.Analyzer{
+	Name: "licenseguard",
+	Doc:  "report imports of packages whose license is denied by the golicenseguard configuration",
+	URL:  "https://github.com/DefangLabs/GoLicenseGuard",
+	Run:  run,
+}
+
+var (
+	configFile  string
+	deny        string
+	failUnknown bool
+	compatible  bool
+	loadCache   sync.Once
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&configFile, "config", "", "configuration file; by default golicenseguard.yaml (or .golicenseguard.yaml or .golicenseguard.json) in the module root")
+	Analyzer.Flags.StringVar(&deny, "deny", "", "comma-separated SPDX license IDs (or substrings of IDs) or categories that must not be imported (default AGPL)")
+	Analyzer.Flags.BoolVar(&failUnknown, "fail-unknown", false, "report imports of packages whose license cannot be determined")
+	Analyzer.Flags.BoolVar(&compatible, "compatibility", false, "report imports whose license is incompatible with the importing package's")
+}
+
+// moduleRoot returns the directory of the go.mod file of the package in dir, or dir if there is none
+func moduleRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// options returns the options to check the package in dir with
+func options(dir string) (licenseguard.Options, error) {
+	o := licenseguard.DefaultOptions()
+	file, required := configFile, configFile != ""
+	if !required {
+		file = licenseguard.FindConfigFileIn(moduleRoot(dir))
+	}
+	var err error
+	if o.Config, err = licenseguard.LoadConfig(file, required); err != nil {
+		return o, err
+	}
+	if deny != "" {
+		o.Deny = strings.Split(deny, ",")
+	}
+	o.FailUnknown = failUnknown
+	o.Compatibility = compatible
+	return o, nil
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if len(pass.Files) == 0 || strings.HasSuffix(pass.Pkg.Path(), "_test") {
+		return nil, nil // external test packages can't be listed by their import path
+	}
+	dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+	o, err := options(dir)
+	if err != nil {
+		return nil, err
+	}
+	o.Args = []string{pass.Pkg.Path()}
+
+	loadCache.Do(func() { licenseguard.LoadLicenseCache() })
+	mr, err := licenseguard.Scan(dir, o)
+	if err != nil {
+		return nil, err
+	}
+	licenseguard.SaveLicenseCache()
+
+	imports := map[licenseguard.ImportPath]*ast.ImportSpec{}
+	for _, f := range pass.Files {
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && imports[licenseguard.ImportPath(path)] == nil {
+				imports[licenseguard.ImportPath(path)] = spec
+			}
+		}
+	}
+	self := licenseguard.ImportPath(pass.Pkg.Path())
+	for _, issue := range mr.Issues {
+		switch {
+		case issue.ImportPath == self && len(issue.Imports) > 0:
+			for _, dep := range issue.Imports {
+				pos := pass.Files[0].Name.Pos()
+				if spec := imports[dep.ImportPath]; spec != nil {
+					pos = spec.Pos()
+				} else if len(dep.Chain) > 1 && imports[dep.Chain[1]] != nil {
+					pos = imports[dep.Chain[1]].Pos() // indirect import, with -trace
+				}
+				pass.Reportf(pos, "%s", importMessage(issue, dep))
+			}
+		case issue.ImportPath == self:
+			pass.Reportf(pass.Files[0].Name.Pos(), "%s", issue.String())
+		case imports[issue.ImportPath] != nil && len(issue.Imports) == 0:
+			// The imported package's own license is the issue, eg. it's not in the allow list
+			pass.Reportf(imports[issue.ImportPath].Pos(), "%s", issue.String())
+		}
+	}
+	return nil, nil
+}
+
+// importMessage describes the import of dep by the package with the issue
+func importMessage(issue licenseguard.Issue, dep licenseguard.Dependency) string {
+	if issue.Kind == licenseguard.IssueIncompatible {
+		msg := dep.License + " licensed package " + string(dep.ImportPath) + " is incompatible with " + issue.License
+		if dep.Rule != "" {
+			msg += ": " + dep.Rule
+		}
+		return msg
+	}
+	return issue.License + " licensed package imports " + dep.License + " licensed package " + string(dep.ImportPath)
+}
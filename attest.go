@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+	"github.com/pkg/errors"
+)
+
+// attestationSubjects returns the -attest-subject files, or else the -binary, or else the go.mod and go.sum files of
+// the module directories
+func attestationSubjects(dirs []string) ([]licenseguard.AttestationSubject, error) {
+	files := []string(attestSubjects)
+	if len(files) == 0 && *binaryFile != "" {
+		files = []string{*binaryFile}
+	}
+	if len(files) == 0 && *preview == "" {
+		for _, dir := range dirs {
+			for _, name := range []string{"go.mod", "go.sum"} {
+				if file := filepath.Join(dir, name); exists(file) {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	var subjects []licenseguard.AttestationSubject
+	for _, file := range files {
+		subject, err := licenseguard.FileSubject(file)
+		if err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects, nil
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// attest writes the in-toto statement of the report to the -attest file, and attaches the report to the -attest-image
+// with cosign attest, which signs it (keyless, unless configured otherwise)
+func attest(report licenseguard.Report, dirs []string) error {
+	if *attestFile != "" {
+		subjects, err := attestationSubjects(dirs)
+		if err != nil {
+			return err
+		}
+		f, err := os.Create(*attestFile)
+		if err != nil {
+			return err
+		}
+		if err := licenseguard.WriteAttestation(f, report, subjects, toolVersion()); err != nil {
+			f.Close()
+			return errors.Wrapf(err, "writing %s", *attestFile)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	if *attestImage != "" {
+		// cosign makes the statement itself, with the image as the subject
+		predicate, err := os.CreateTemp("", "golicenseguard-predicate-*.json")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(predicate.Name())
+		err = json.NewEncoder(predicate).Encode(licenseguard.NewLicenseScanPredicate(report, toolVersion()))
+		if closeErr := predicate.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("cosign", "attest", "--yes", "--type", licenseguard.LicenseScanPredicateType, "--predicate", predicate.Name(), *attestImage)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errors.Wrapf(err, "attesting %s with cosign", *attestImage)
+		}
+	}
+	return nil
+}
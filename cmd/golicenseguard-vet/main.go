@@ -0,0 +1,15 @@
+// Command golicenseguard-vet runs the license check of golicenseguard as a vet tool, eg.
+//
+//	go vet -vettool=$(which golicenseguard-vet) ./...
+//
+// or standalone, like golicenseguard-vet ./...
+package main
+
+import (
+	"github.com/DefangLabs/GoLicenseGuard/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
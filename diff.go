@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+)
+
+// diffReports writes the packages that were added or removed and the ones whose license changed between the old and
+// the new JSON report (or baseline), without checking any packages. It returns whether anything changed.
+func diffReports(oldFile, newFile string) (bool, error) {
+	old, err := licenseguard.LoadBaseline(oldFile)
+	if err != nil {
+		return false, err
+	}
+	newer, err := licenseguard.LoadBaseline(newFile)
+	if err != nil {
+		return false, err
+	}
+	report := licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion, BaselineDiff: licenseguard.DiffBaselines(old, newer)}
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report.BaselineDiff)
+	} else {
+		report.WriteText(os.Stdout, os.Stderr, licenseguard.TextOptions{})
+	}
+	return !report.BaselineDiff.Empty(), err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+	"github.com/pkg/errors"
+)
+
+var githubClient = &http.Client{Timeout: 30 * time.Second}
+
+// pullRequestNumber returns the number of the pull request of the GitHub Actions workflow run, from its event
+func pullRequestNumber() (int, error) {
+	file := os.Getenv("GITHUB_EVENT_PATH")
+	if file == "" {
+		return 0, errors.New("GITHUB_EVENT_PATH is not set; not running in GitHub Actions?")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, errors.Wrap(err, "reading the workflow event")
+	}
+	var event struct {
+		Number      int `json:"number"`
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0, errors.Wrap(err, "parsing the workflow event")
+	}
+	if event.PullRequest.Number == 0 {
+		return 0, errors.New("the workflow was not triggered by a pull request")
+	}
+	return event.PullRequest.Number, nil
+}
+
+// githubRequest sends a GitHub REST API request with the GITHUB_TOKEN and decodes the response into result, if any
+func githubRequest(method, path string, body, result any) error {
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = "https://api.github.com"
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, api+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("GITHUB_TOKEN"))
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(result), "decoding %s %s", method, path)
+}
+
+// postGitHubComment posts the violations of the report as a comment on the pull request of the workflow run, or
+// updates the comment of an earlier run
+func postGitHubComment(report licenseguard.Report) error {
+	repo := os.Getenv("GITHUB_REPOSITORY")
+	if repo == "" || os.Getenv("GITHUB_TOKEN") == "" {
+		return errors.New("-github-comment needs GITHUB_REPOSITORY and GITHUB_TOKEN")
+	}
+	number, err := pullRequestNumber()
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	licenseguard.WriteGitHubComment(&body, report)
+	comment := map[string]string{"body": body.String()}
+
+	var comments []struct {
+		Id   int64  `json:"id"`
+		Body string `json:"body"`
+	}
+	if err := githubRequest("GET", fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=100", repo, number), nil, &comments); err != nil {
+		return err
+	}
+	for _, c := range comments {
+		if strings.HasPrefix(c.Body, licenseguard.GitHubCommentMarker) {
+			return githubRequest("PATCH", fmt.Sprintf("/repos/%s/issues/comments/%d", repo, c.Id), comment, nil)
+		}
+	}
+	return githubRequest("POST", fmt.Sprintf("/repos/%s/issues/%d/comments", repo, number), comment, nil)
+}
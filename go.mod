@@ -1,8 +1,16 @@
 module github.com/DefangLabs/GoLicenseGuard
 
-go 1.21.3
+go 1.25.0
 
 require (
 	github.com/google/licensecheck v0.3.1
 	github.com/pkg/errors v0.9.1
+	golang.org/x/tools v0.44.0
+)
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/mod v0.35.0
+	golang.org/x/sync v0.20.0 // indirect
 )
@@ -0,0 +1,79 @@
+package licenseguard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LicenseScanPredicateType is the predicate type of the in-toto attestations written by WriteAttestation
+const LicenseScanPredicateType = "https://github.com/DefangLabs/GoLicenseGuard/license-scan/v1"
+
+const inTotoStatementType = "https://in-toto.io/Statement/v1"
+
+// AttestationSubject is an artifact an attestation is about, like a binary or a go.sum file
+type AttestationSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// FileSubject returns the subject for a file, with its SHA-256 digest
+func FileSubject(file string) (AttestationSubject, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return AttestationSubject{}, errors.Wrap(err, "attestation subject")
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return AttestationSubject{}, errors.Wrapf(err, "hashing %s", file)
+	}
+	return AttestationSubject{Name: filepath.ToSlash(file), Digest: map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))}}, nil
+}
+
+// LicenseScanPredicate is the predicate of a license scan attestation: the scanner, whether the policy was met and the
+// report
+type LicenseScanPredicate struct {
+	Scanner struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"scanner"`
+	Timestamp string `json:"timestamp"`
+	Compliant bool   `json:"compliant"` // none of the modules have issues
+	Report    Report `json:"report"`
+}
+
+// NewLicenseScanPredicate returns the predicate for the report
+func NewLicenseScanPredicate(report Report, toolVersion string) LicenseScanPredicate {
+	p := LicenseScanPredicate{Timestamp: time.Now().UTC().Format(time.RFC3339), Compliant: true, Report: report}
+	p.Scanner.Name, p.Scanner.Version = "golicenseguard", toolVersion
+	for _, m := range report.Modules {
+		if len(m.Issues) > 0 || m.Error != "" {
+			p.Compliant = false
+		}
+	}
+	return p
+}
+
+// WriteAttestation writes the report as an (unsigned) in-toto statement about the subjects, with a
+// LicenseScanPredicateType predicate
+func WriteAttestation(w io.Writer, report Report, subjects []AttestationSubject, toolVersion string) error {
+	if len(subjects) == 0 {
+		return errors.New("an attestation needs at least one subject")
+	}
+	statement := struct {
+		Type          string               `json:"_type"`
+		Subject       []AttestationSubject `json:"subject"`
+		PredicateType string               `json:"predicateType"`
+		Predicate     LicenseScanPredicate `json:"predicate"`
+	}{inTotoStatementType, subjects, LicenseScanPredicateType, NewLicenseScanPredicate(report, toolVersion)}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(statement)
+}
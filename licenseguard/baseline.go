@@ -0,0 +1,199 @@
+package licenseguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Baseline is the license of each package and the issues from an earlier run (-baseline)
+type Baseline struct {
+	Licenses map[ImportPath]string `json:"licenses"`
+	Versions map[ImportPath]string `json:"versions,omitempty"` // module version of the packages that have one
+	Issues   []string              `json:"issues,omitempty"`   // see issueKeys
+}
+
+// issueKeys identifies the issue in a baseline: the kind and the package, and each import for issues about imports,
+// so an issue with a new denied import is still reported
+func issueKeys(issue Issue) []string {
+	key := string(issue.Kind) + " " + string(issue.ImportPath)
+	if len(issue.Imports) == 0 {
+		return []string{key}
+	}
+	keys := make([]string, len(issue.Imports))
+	for i, imp := range issue.Imports {
+		keys[i] = key + " -> " + string(imp.ImportPath)
+	}
+	return keys
+}
+
+// BaselineDiff is the difference between the licenses in a baseline file and the current report (-baseline)
+type BaselineDiff struct {
+	Added   []Dependency    `json:"added,omitempty"`   // packages that are not in the baseline
+	Removed []Dependency    `json:"removed,omitempty"` // packages in the baseline that are gone, with their old license
+	Changed []LicenseChange `json:"changed,omitempty"` // packages with a different license than in the baseline
+}
+
+// LicenseChange is a package whose license changed since the baseline
+type LicenseChange struct {
+	ImportPath ImportPath `json:"importPath"`
+	Old        string     `json:"old"`
+	New        string     `json:"new"`
+	OldVersion string     `json:"oldVersion,omitempty"` // module version in the baseline, if it was recorded
+	NewVersion string     `json:"newVersion,omitempty"`
+}
+
+// Empty reports whether nothing changed since the baseline
+func (d *BaselineDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// reportBaseline returns the license and version of each package in the report and its issues, including any that
+// were suppressed by an earlier baseline
+func reportBaseline(report Report) *Baseline {
+	baseline := &Baseline{Licenses: map[ImportPath]string{}, Versions: map[ImportPath]string{}}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			baseline.Licenses[pr.ImportPath] = pr.License
+			if pr.Version != "" {
+				baseline.Versions[pr.ImportPath] = pr.Version
+			}
+		}
+		for _, issue := range append(m.Issues, m.Suppressed...) {
+			for _, key := range issueKeys(issue) {
+				baseline.Issues = appendUnique(baseline.Issues, key)
+			}
+		}
+	}
+	sort.Strings(baseline.Issues)
+	return baseline
+}
+
+// WriteBaseline writes the license of each package in the report and its issues to the baseline file
+func WriteBaseline(file string, report Report) error {
+	baseline := reportBaseline(report)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep the "->" of the issues readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(baseline); err != nil { // licenses sorted by import path
+		return err
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return errors.Wrapf(err, "writing baseline %s", file)
+	}
+	return nil
+}
+
+// LoadBaseline reads a baseline file written by WriteBaseline, or a JSON report (-json); a file with only the licenses
+// (a JSON object mapping import paths to licenses, as written by earlier versions) has no issues
+func LoadBaseline(file string) (*Baseline, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading baseline %s", file)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err == nil && report.Modules != nil {
+		return reportBaseline(report), nil
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil || baseline.Licenses == nil {
+		baseline = Baseline{}
+		if err := json.Unmarshal(data, &baseline.Licenses); err != nil {
+			return nil, errors.Wrapf(err, "parsing baseline %s", file)
+		}
+	}
+	return &baseline, nil
+}
+
+// Suppress moves the issues of the report that are in the baseline to the Suppressed issues of their module; an
+// issue about imports is split, so only its new imports remain an issue. It returns the number of suppressed issues.
+func (b *Baseline) Suppress(report *Report) int {
+	suppressed := 0
+	for i := range report.Modules {
+		m := &report.Modules[i]
+		var issues []Issue
+		for _, issue := range m.Issues {
+			keys := issueKeys(issue)
+			if len(issue.Imports) == 0 {
+				if slices.Contains(b.Issues, keys[0]) {
+					m.Suppressed = append(m.Suppressed, issue)
+					suppressed++
+				} else {
+					issues = append(issues, issue)
+				}
+				continue
+			}
+			known, added := issue, issue
+			known.Imports, added.Imports = nil, nil
+			for j, imp := range issue.Imports {
+				if slices.Contains(b.Issues, keys[j]) {
+					known.Imports = append(known.Imports, imp)
+				} else {
+					added.Imports = append(added.Imports, imp)
+				}
+			}
+			if len(known.Imports) > 0 {
+				m.Suppressed = append(m.Suppressed, known)
+				suppressed++
+			}
+			if len(added.Imports) > 0 {
+				issues = append(issues, added)
+			}
+		}
+		m.Issues = issues
+	}
+	return suppressed
+}
+
+// DiffBaseline compares the licenses in the report with the baseline
+func DiffBaseline(baseline *Baseline, report Report) *BaselineDiff {
+	return DiffBaselines(baseline, reportBaseline(report))
+}
+
+// DiffBaselines compares the licenses in two baselines, eg. loaded from the JSON reports of two releases (-diff)
+func DiffBaselines(baseline, newer *Baseline) *BaselineDiff {
+	current := newer.Licenses
+	diff := &BaselineDiff{}
+	for importPath, lic := range current {
+		old, ok := baseline.Licenses[importPath]
+		if !ok {
+			diff.Added = append(diff.Added, Dependency{ImportPath: importPath, License: lic})
+		} else if old != lic {
+			diff.Changed = append(diff.Changed, LicenseChange{ImportPath: importPath, Old: old, New: lic,
+				OldVersion: baseline.Versions[importPath], NewVersion: newer.Versions[importPath]})
+		}
+	}
+	for importPath, lic := range baseline.Licenses {
+		if _, ok := current[importPath]; !ok {
+			diff.Removed = append(diff.Removed, Dependency{ImportPath: importPath, License: lic})
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].ImportPath < diff.Added[j].ImportPath })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].ImportPath < diff.Removed[j].ImportPath })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].ImportPath < diff.Changed[j].ImportPath })
+	return diff
+}
+
+// writeText writes the changes since the baseline, one package per line
+func (d *BaselineDiff) writeText(w io.Writer) {
+	for _, c := range d.Changed {
+		var versions string
+		if c.OldVersion != c.NewVersion && c.OldVersion != "" && c.NewVersion != "" {
+			versions = fmt.Sprintf(" (%s -> %s)", c.OldVersion, c.NewVersion)
+		}
+		fmt.Fprintf(w, "license of package %s changed from %s to %s%s\n", c.ImportPath, c.Old, c.New, versions)
+	}
+	for _, dep := range d.Added {
+		fmt.Fprintf(w, "%s licensed package %s was added\n", dep.License, dep.ImportPath)
+	}
+	for _, dep := range d.Removed {
+		fmt.Fprintf(w, "%s licensed package %s was removed\n", dep.License, dep.ImportPath)
+	}
+}
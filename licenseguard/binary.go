@@ -0,0 +1,193 @@
+package licenseguard
+
+import (
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// downloadedModule is the output of go mod download -json
+type downloadedModule struct {
+	Path    string
+	Version string
+	Dir     string
+	Error   string
+}
+
+// downloadModules returns the module cache directories of the modules (path@version), downloading them if needed.
+// Modules that can't be downloaded have an Error instead.
+func downloadModules(ctx context.Context, modules []string) (map[string]downloadedModule, error) {
+	result := map[string]downloadedModule{}
+	if len(modules) == 0 {
+		return result, nil
+	}
+	// go mod download fails if any module fails, but still reports the others
+	cmd := goCommand(ctx, os.TempDir(), []string{"GOFLAGS=-mod=mod", "GO111MODULE=on"}, append([]string{"mod", "download", "-json"}, modules...)...)
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, errors.Wrap(ctx.Err(), "go mod download")
+	}
+	dec := json.NewDecoder(strings.NewReader(string(out)))
+	for {
+		var m downloadedModule
+		if decErr := dec.Decode(&m); decErr == io.EOF {
+			break
+		} else if decErr != nil {
+			if err != nil {
+				return nil, errors.Wrap(err, "go mod download")
+			}
+			return nil, errors.Wrap(decErr, "parsing go mod download output")
+		}
+		result[m.Path+"@"+m.Version] = m
+	}
+	return result, nil
+}
+
+// rootGoFiles returns the non-test .go files in dir, for the license headers of a module's root
+func rootGoFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var names []string
+	for _, file := range files {
+		if name := filepath.Base(file); !strings.HasSuffix(name, "_test.go") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ScanBinary checks the licenses of the modules that are compiled into a Go binary, from its build information: each
+// module is looked up in the module cache (and downloaded if needed), and is reported as a package with the module
+// path, imported by the binary's main package. The main module is only checked if it has a version (eg. go install).
+func ScanBinary(file string, opts Options) (*ModuleReport, error) {
+	o := &opts
+	bi, err := buildinfo.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading build information of %s", file)
+	}
+	ctx, cancel := withTimeout(withGoCommandLog(context.Background(), o), o.Timeout)
+	defer cancel()
+
+	// The module of each dependency, after replacements; local replacements have no version and can't be checked
+	modules := map[ImportPath]*Module{}
+	for _, dep := range bi.Deps {
+		if o.isExcluded(ImportPath(dep.Path)) {
+			continue
+		}
+		m := &Module{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			m.Replace = &Module{Path: dep.Replace.Path, Version: dep.Replace.Version}
+			if dep.Replace.Version == "" {
+				m.Replace.Dir = dep.Replace.Path // a local directory
+			}
+		}
+		modules[ImportPath(dep.Path)] = m
+	}
+	mainModule := &Module{Path: bi.Main.Path, Version: bi.Main.Version, Main: true}
+	if bi.Main.Version == "(devel)" || bi.Main.Version == "" {
+		mainModule.Version = ""
+	}
+	return scanModules(ctx, file, ImportPath(bi.Path), mainModule, modules, o)
+}
+
+// scanModules checks the modules (by their path) in the module cache, downloading them if needed; they are reported
+// as packages imported by the root package of the main module (with the import path root)
+func scanModules(ctx context.Context, name string, root ImportPath, mainModule *Module, modules map[ImportPath]*Module, o *Options) (*ModuleReport, error) {
+	if err := o.useLicenses(); err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, m := range append(slices.Collect(maps.Values(modules)), mainModule) {
+		source := m
+		if m.Replace != nil {
+			source = m.Replace
+		}
+		if source.Version != "" && source.Dir == "" {
+			versions = append(versions, source.Path+"@"+source.Version)
+		}
+	}
+	downloaded, err := downloadModules(ctx, versions)
+	if err != nil {
+		return nil, errors.Wrapf(timeoutError(err, o.Timeout), "downloading the modules of %s", name)
+	}
+
+	report := &ModuleReport{Dir: name}
+	byImportPath := map[ImportPath]*Package{}
+	var errs = map[ImportPath]string{} // modules that could not be downloaded
+	resolve := func(importPath ImportPath, m *Module) *Package {
+		p := &Package{ImportPath: string(importPath), Module: m, DepOnly: !m.Main}
+		source := m
+		if m.Replace != nil {
+			source = m.Replace
+		}
+		if source.Dir != "" {
+			p.Dir = source.Dir
+		} else if d, ok := downloaded[source.Path+"@"+source.Version]; ok && d.Error == "" {
+			p.Dir, source.Dir = d.Dir, d.Dir
+		} else if ok {
+			errs[importPath] = d.Error
+		}
+		if p.Dir != "" {
+			p.GoFiles = rootGoFiles(p.Dir) // not the files of the current directory
+		}
+		return p
+	}
+	for importPath, m := range modules {
+		byImportPath[importPath] = resolve(importPath, m)
+	}
+	rootPackage := resolve(root, mainModule)
+	if rootPackage.Dir == "" {
+		rootPackage.license, rootPackage.licenseSource = UnknownLicense, LicenseSourceOverride // source not available; only check its imports
+	}
+	for importPath := range modules {
+		rootPackage.Imports = append(rootPackage.Imports, string(importPath))
+	}
+	byImportPath[root] = rootPackage
+
+	depLicense := func(pkg ImportPath) (string, string, bool) {
+		p := byImportPath[pkg]
+		if p == nil {
+			return "", "", false
+		}
+		lic, _ := p.FindLicense(o)
+		return lic, p.licenseURL, true
+	}
+	resetLicenseDirCache()
+	resolveLicenses(byImportPath, o)
+	for _, importPath := range sortedImportPaths(byImportPath) {
+		p := byImportPath[importPath]
+		res := checkPackage(importPath, p, depLicense, o)
+		if msg, ok := errs[importPath]; ok && res.pkg != nil {
+			res.pkg.Error = msg
+		}
+		if p == rootPackage && rootPackage.Dir == "" {
+			// nothing to report about the binary's own license, which is unknown; only about its imports
+			res.pkg = nil
+			ownIssue := func(issue Issue) bool { return len(issue.Imports) == 0 }
+			res.issues, res.warnings, res.errors = slices.DeleteFunc(res.issues, ownIssue), slices.DeleteFunc(res.warnings, ownIssue), nil
+		}
+		report.add(res)
+	}
+	if err := o.stopOnError(report); err != nil {
+		return nil, err
+	}
+	if len(o.Config.Rules) > 0 {
+		importOf := map[ImportPath][]ImportPath{}
+		for importPath := range modules {
+			importOf[importPath] = []ImportPath{root}
+		}
+		if err := o.Config.applyRules(report, importOf); err != nil {
+			return nil, err
+		}
+	}
+	report.LicenseFiles = groupLicenseFiles(byImportPath)
+	report.Issues, report.Warnings = o.Config.applySeverities(report.Issues, report.Warnings)
+	return report, nil
+}
@@ -0,0 +1,101 @@
+package licenseguard
+
+import (
+	"sort"
+	"strings"
+)
+
+// LicenseCategory is a kind of license, for rules like "deny all strong copyleft licenses"
+type LicenseCategory string
+
+const (
+	CategoryPublicDomain    LicenseCategory = "public-domain"    // no conditions at all
+	CategoryPermissive      LicenseCategory = "permissive"       // attribution only
+	CategoryWeakCopyleft    LicenseCategory = "weak-copyleft"    // changes to the licensed files must be shared
+	CategoryStrongCopyleft  LicenseCategory = "strong-copyleft"  // the whole program must be shared when distributed
+	CategoryNetworkCopyleft LicenseCategory = "network-copyleft" // the whole program must be shared with network users
+	CategoryProprietary     LicenseCategory = "proprietary"      // source available, but with usage restrictions
+	CategoryUnknown         LicenseCategory = "unknown"          // unrecognized license ID
+)
+
+// licenseCategories maps license ID prefixes to their category; the longest matching prefix wins
+var licenseCategories = map[string]LicenseCategory{
+	"0BSD":         CategoryPermissive,
+	"AGPL":         CategoryNetworkCopyleft,
+	"Apache":       CategoryPermissive,
+	"BSD":          CategoryPermissive,
+	"BSL-1.0":      CategoryPermissive, // Boost
+	"BUSL":         CategoryProprietary,
+	"CC-BY-4.0":    CategoryPermissive,
+	"CC0":          CategoryPublicDomain,
+	"CDDL":         CategoryWeakCopyleft,
+	"CPL":          CategoryWeakCopyleft,
+	"Elastic":      CategoryProprietary,
+	"EPL":          CategoryWeakCopyleft,
+	"EUPL":         CategoryStrongCopyleft,
+	"GPL":          CategoryStrongCopyleft,
+	"ISC":          CategoryPermissive,
+	"LGPL":         CategoryWeakCopyleft,
+	"MIT":          CategoryPermissive,
+	"MPL":          CategoryWeakCopyleft,
+	"NCSA":         CategoryPermissive,
+	"OSL":          CategoryNetworkCopyleft,
+	"PostgreSQL":   CategoryPermissive,
+	"Python":       CategoryPermissive,
+	"SSPL":         CategoryNetworkCopyleft,
+	"Unicode":      CategoryPermissive,
+	"Unlicense":    CategoryPublicDomain,
+	"X11":          CategoryPermissive,
+	"Zlib":         CategoryPermissive,
+	UnknownLicense: CategoryUnknown,
+}
+
+var licenseCategoryNames = map[string]bool{
+	string(CategoryPublicDomain): true, string(CategoryPermissive): true, string(CategoryWeakCopyleft): true,
+	string(CategoryStrongCopyleft): true, string(CategoryNetworkCopyleft): true, string(CategoryProprietary): true,
+	string(CategoryUnknown): true,
+}
+
+// categoryOrder orders the license categories from least to most restrictive; unknown is treated as the most
+var categoryOrder = []LicenseCategory{CategoryPublicDomain, CategoryPermissive, CategoryWeakCopyleft, CategoryStrongCopyleft,
+	CategoryNetworkCopyleft, CategoryProprietary, CategoryUnknown}
+
+// isLicenseCategory reports whether the name is one of the license categories, eg. "strong-copyleft"
+func isLicenseCategory(name string) bool {
+	return licenseCategoryNames[strings.ToLower(name)]
+}
+
+// Category returns the category of the license (without any WITH exception), or CategoryUnknown
+func Category(lic string) LicenseCategory {
+	lic, _ = splitException(lic)
+	if category, ok := customCategory(lic); ok {
+		return category
+	}
+	var best string
+	for prefix := range licenseCategories {
+		if strings.HasPrefix(lic, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return CategoryUnknown
+	}
+	return licenseCategories[best]
+}
+
+// matchLicense reports whether the license (with an optional exception) matches the ID (or ID WITH exception) or, if
+// it's a category name, is in that category; see matchLicenseTerm
+func matchLicense(lic, idOrCategory string) bool {
+	return matchLicenseTerm(lic, idOrCategory, false)
+}
+
+// expressionCategories returns the distinct categories of the licenses in the SPDX expression, sorted and joined
+// with ", "
+func expressionCategories(expr string) string {
+	var categories []string
+	for _, lic := range licenseTerms(expr) {
+		categories = appendUnique(categories, string(Category(lic)))
+	}
+	sort.Strings(categories)
+	return strings.Join(categories, ", ")
+}
@@ -0,0 +1,106 @@
+package licenseguard
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BundledLicense is the license of C (or C++) code that is bundled with a cgo package, like a library vendored in a
+// third_party directory, which is compiled into the package but doesn't need to have the package's license
+type BundledLicense struct {
+	File    string `json:"file"`    // license file or source file, relative to the package directory
+	License string `json:"license"` // license ID or expression
+}
+
+// sourceFiles returns the .go files of the package, including its cgo files
+func (p *Package) sourceFiles() []string {
+	if len(p.CgoFiles) == 0 {
+		return p.GoFiles
+	}
+	return append(slices.Clone(p.GoFiles), p.CgoFiles...)
+}
+
+// usesCgo returns whether the package has cgo files or C, C++ or header files that are compiled with it
+func (p *Package) usesCgo() bool {
+	return len(p.CgoFiles) > 0 || len(p.CFiles) > 0 || len(p.CXXFiles) > 0 || len(p.HFiles) > 0
+}
+
+// findBundledLicenses returns the licenses of the C sources of a cgo package that differ from the package's license:
+// the license headers of its C, C++ and header files, and the license files in its subdirectories, like third_party/
+// zlib/LICENSE. Subdirectories with .go files are skipped; they are packages of their own.
+func (p *Package) findBundledLicenses(lic string, minConfidence int, lc *licenseCorpus) []BundledLicense {
+	if !p.usesCgo() {
+		return nil
+	}
+	var bundled []BundledLicense
+	add := func(file, id string) {
+		if id != "" && id != lic {
+			bundled = append(bundled, BundledLicense{File: file, License: id})
+		}
+	}
+	for _, file := range slices.Concat(p.CFiles, p.CXXFiles, p.HFiles) {
+		id, err := readSPDXTag(filepath.Join(p.Dir, file))
+		if err == nil && id == "" {
+			if match, err := readLicenseHeader(filepath.Join(p.Dir, file), lc); err == nil {
+				id = match.ID
+			}
+		}
+		add(file, id)
+	}
+	filepath.WalkDir(p.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == p.Dir {
+			return nil
+		}
+		switch name := d.Name(); {
+		case name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"):
+			return filepath.SkipDir
+		case hasGoFiles(path):
+			return filepath.SkipDir
+		}
+		if file, err := findLicenseFileCached(path); err == nil {
+			if match, err := readLicenseFileCached(file, minConfidence, lc); err == nil {
+				rel, _ := filepath.Rel(p.Dir, file)
+				add(filepath.ToSlash(rel), match.ID)
+			}
+		}
+		return nil
+	})
+	return bundled
+}
+
+// hasGoFiles returns whether dir contains .go files or a go.mod file
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".go") || entry.Name() == "go.mod") {
+			return true
+		}
+	}
+	return false
+}
+
+// bundledLicenses returns the distinct licenses of the bundled code
+func bundledLicenses(bundled []BundledLicense) []string {
+	var licenses []string
+	for _, b := range bundled {
+		licenses = appendUnique(licenses, b.License)
+	}
+	return licenses
+}
+
+// bundledFiles returns the files of the bundled code with the license, joined with ", "
+func bundledFiles(bundled []BundledLicense, lic string) string {
+	var files []string
+	for _, b := range bundled {
+		if b.License == lic {
+			files = append(files, b.File)
+		}
+	}
+	return strings.Join(files, ", ")
+}
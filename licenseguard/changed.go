@@ -0,0 +1,74 @@
+package licenseguard
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/modfile"
+)
+
+// ScanChanged checks only the modules of the go.sum in dir that were added, or whose version changed, since the git
+// revision (eg. HEAD before a commit, or @{upstream} before a push), like ScanGoSum but imported by the module in dir.
+// It doesn't run go list, so it's fast enough for git hooks; the report has no packages if go.sum didn't change.
+func ScanChanged(dir, since string, opts Options) (*ModuleReport, error) {
+	o := &opts
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	modulePath := modfile.ModulePath(goMod)
+	if modulePath == "" {
+		return nil, errors.Errorf("%s has no module path", filepath.Join(dir, "go.mod"))
+	}
+	current := map[ImportPath]*Module{}
+	if f, err := os.Open(filepath.Join(dir, "go.sum")); err == nil {
+		current, err = readGoSum(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading go.sum")
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	previous, err := readGoSumAt(dir, since)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[ImportPath]*Module{}
+	for importPath, m := range current {
+		if prev := previous[importPath]; (prev == nil || prev.Version != m.Version) && !o.isExcluded(importPath) {
+			modules[importPath] = m
+		}
+	}
+	if o.Log != nil {
+		o.Log.Info("changed modules of go.sum", "changed", len(modules), "modules", len(current), "since", since)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := withTimeout(withGoCommandLog(context.Background(), o), o.Timeout)
+	defer cancel()
+	return scanModules(ctx, dir, ImportPath(modulePath), &Module{Path: modulePath, Main: true, Dir: absDir}, modules, o)
+}
+
+// readGoSumAt returns the modules of the go.sum file in dir at the git revision; none if it had no go.sum then, like
+// before the first commit of the module
+func readGoSumAt(dir, rev string) (map[ImportPath]*Module, error) {
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return nil, errors.Wrapf(err, "unknown git revision %s", rev)
+	}
+	if _, err := git(dir, "cat-file", "-e", rev+":./go.sum"); err != nil {
+		return map[ImportPath]*Module{}, nil
+	}
+	out, err := git(dir, "show", rev+":./go.sum")
+	if err != nil {
+		return nil, err
+	}
+	modules, err := readGoSum(bytes.NewReader(out))
+	return modules, errors.Wrapf(err, "reading go.sum of %s", rev)
+}
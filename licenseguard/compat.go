@@ -0,0 +1,177 @@
+package licenseguard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// permissiveIncompatible are the licenses that can't be used by permissively licensed code without relicensing it
+var permissiveIncompatible = []string{"GPL-2.0", "GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0", "GPL-3.0-only", "GPL-3.0-or-later", "AGPL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later"}
+
+// DefaultIncompatible is the compatibility matrix used with -compatibility: for the license of an importing package,
+// the licenses its imports can't have. Only the common cases are covered; licenses that aren't listed are compatible.
+var DefaultIncompatible = map[string][]string{
+	"0BSD":         permissiveIncompatible,
+	"Apache-2.0":   permissiveIncompatible,
+	"BSD-2-Clause": permissiveIncompatible,
+	"BSD-3-Clause": permissiveIncompatible,
+	"ISC":          permissiveIncompatible,
+	"MIT":          permissiveIncompatible,
+	"MPL-2.0":      permissiveIncompatible,
+	"Unlicense":    permissiveIncompatible,
+	// The Apache license's patent terms are incompatible with GPLv2, as is GPLv3 with GPLv2-only
+	"GPL-2.0":      {"Apache-2.0", "GPL-3.0", "GPL-3.0-only", "GPL-3.0-or-later", "AGPL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "LGPL-3.0", "LGPL-3.0-only", "LGPL-3.0-or-later"},
+	"GPL-2.0-only": {"Apache-2.0", "GPL-3.0", "GPL-3.0-only", "GPL-3.0-or-later", "AGPL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "LGPL-3.0", "LGPL-3.0-only", "LGPL-3.0-or-later"},
+	"GPL-3.0":      {"GPL-2.0-only"},
+	"GPL-3.0-only": {"GPL-2.0-only"},
+}
+
+// compatibilityMatrix returns the configured compatibility matrix, the default one with -compatibility, or nil
+func (o *Options) compatibilityMatrix() map[string][]string {
+	if len(o.Config.Incompatible) > 0 {
+		return o.Config.Incompatible
+	}
+	if o.Compatibility {
+		return DefaultIncompatible
+	}
+	return nil
+}
+
+// incompatibility returns the rule of the matrix that forbids a package licensed under importer to import a package
+// licensed under dep, or "" if there is a choice of licenses in the two expressions that the matrix allows. License
+// exceptions are ignored.
+func incompatibility(matrix map[string][]string, importer, dep string) string {
+	if importer == UnknownLicense || dep == UnknownLicense {
+		return "" // see FailUnknown
+	}
+	compatible := licenseSatisfied(importer, func(il string) bool {
+		il, _ = splitException(il)
+		incompatible := lookupLicense(matrix, il)
+		return licenseSatisfied(dep, func(dl string) bool {
+			dl, _ = splitException(dl)
+			return !containsFold(incompatible, dl)
+		})
+	})
+	if compatible {
+		return ""
+	}
+	for _, il := range licenseTerms(importer) {
+		il, _ = splitException(il)
+		for _, dl := range licenseTerms(dep) {
+			if dl, _ = splitException(dl); containsFold(lookupLicense(matrix, il), dl) {
+				return incompatibilityRule(il, dl)
+			}
+		}
+	}
+	return "incompatible licenses"
+}
+
+// incompatibilityRule explains why code licensed under il can't use code licensed under dl
+func incompatibilityRule(il, dl string) string {
+	rule := fmt.Sprintf("%s code can't use %s code", il, dl)
+	switch category := Category(dl); {
+	case strings.HasPrefix(il, "GPL-2.0") && strings.HasPrefix(dl, "Apache-2.0"):
+		return rule + ": the patent terms of Apache-2.0 are an additional restriction that GPL-2.0 doesn't allow"
+	case category == CategoryStrongCopyleft || category == CategoryNetworkCopyleft:
+		return rule + fmt.Sprintf(": %s is %s, so the combined work would have to be %s", dl, strings.ReplaceAll(string(category), "-", " "), dl)
+	}
+	return rule
+}
+
+func containsFold(licenses []string, lic string) bool {
+	for _, l := range licenses {
+		if strings.EqualFold(l, lic) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkModuleLicenses checks all dependencies of each main module against the license of the module, from the license
+// file at its root, with the compatibility matrix (or DefaultIncompatible); for Options.ModuleLicense
+func checkModuleLicenses(byImportPath map[ImportPath]*Package, depLicense func(ImportPath) (string, string, bool), o *Options) (issues, warnings []Issue) {
+	matrix := o.compatibilityMatrix()
+	if matrix == nil {
+		matrix = DefaultIncompatible
+	}
+	// The packages of each main module, and their dependencies
+	packages := map[string][]*Package{}
+	var modules []string
+	for _, importPath := range sortedImportPaths(byImportPath) {
+		p := byImportPath[importPath]
+		if p.Module == nil || !p.Module.Main || o.isSkipped(p) {
+			continue
+		}
+		if packages[p.Module.Path] == nil {
+			modules = append(modules, p.Module.Path)
+		}
+		packages[p.Module.Path] = append(packages[p.Module.Path], p)
+	}
+	for _, path := range modules {
+		root := packages[path][0].Module
+		lic, url, err := moduleRootLicense(root.Dir, o)
+		if err != nil {
+			warnings = append(warnings, Issue{Kind: IssueUnknownLicense, ImportPath: ImportPath(path), License: UnknownLicense, Message: "module root: " + err.Error(), Module: path})
+			continue
+		}
+		if o.isDenied(lic, url) {
+			continue // like checkPackage
+		}
+		direct := map[ImportPath]bool{}
+		deps := map[string]bool{}
+		for _, p := range packages[path] {
+			for _, imp := range p.Imports {
+				direct[normalizeImportPath(imp)] = true
+			}
+			for _, dep := range p.Deps {
+				deps[string(normalizeImportPath(dep))] = true
+			}
+		}
+		var incompatible []Dependency
+		for _, dep := range sortedKeys(deps) {
+			pkg := ImportPath(dep)
+			if p := byImportPath[pkg]; p == nil || p.Module != nil && p.Module.Path == path || o.isIgnored(pkg) {
+				continue
+			}
+			depLic, depURL, ok := depLicense(pkg)
+			if !ok || o.isDenied(depLic, depURL) {
+				continue // denied imports are reported by checkPackage
+			}
+			if rule := incompatibility(matrix, lic, depLic); rule != "" {
+				incompatible = append(incompatible, Dependency{ImportPath: pkg, License: depLic, Indirect: !direct[pkg], Rule: rule})
+			}
+		}
+		if len(incompatible) > 0 {
+			issues = append(issues, Issue{Kind: IssueIncompatible, ImportPath: ImportPath(path), License: lic, Imports: incompatible, Module: path})
+		}
+	}
+	return issues, warnings
+}
+
+// moduleRootLicense returns the license (and its URL, if identified by it) of the license file in the module root
+func moduleRootLicense(dir string, o *Options) (string, string, error) {
+	file, err := findLicenseFileCached(dir)
+	if err != nil {
+		return "", "", err
+	}
+	match, err := readLicenseFileCached(file, o.MinConfidence, o.corpus)
+	if err != nil {
+		return "", "", errors.Wrap(err, file)
+	}
+	return match.ID, match.URL, nil
+}
+
+// lookupLicense returns the matrix entry for the license, ignoring case
+func lookupLicense(matrix map[string][]string, lic string) []string {
+	if licenses, ok := matrix[lic]; ok {
+		return licenses
+	}
+	for key, licenses := range matrix {
+		if strings.EqualFold(key, lic) {
+			return licenses
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package licenseguard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the configuration file that is used if it exists
+const DefaultConfigFile = "golicenseguard.yaml"
+
+// configFileNames are the configuration files that are looked for, in order; JSON is valid YAML
+var configFileNames = []string{DefaultConfigFile, ".golicenseguard.yaml", ".golicenseguard.json"}
+
+// FindConfigFile returns the first of the configuration files that exists, or DefaultConfigFile if none does
+func FindConfigFile() string {
+	return FindConfigFileIn("")
+}
+
+// FindConfigFileIn is FindConfigFile for the configuration files in dir
+func FindConfigFileIn(dir string) string {
+	for _, name := range configFileNames {
+		if file := filepath.Join(dir, name); exists(file) {
+			return file
+		}
+	}
+	return filepath.Join(dir, DefaultConfigFile)
+}
+
+func exists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// Config is the contents of the golicenseguard.yaml configuration file
+type Config struct {
+	// Allow lists the licenses that are allowed; if set, packages with any other license are reported
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny lists the licenses (or substrings of licenses) that are denied, if Options.Deny is not set
+	Deny []string `yaml:"deny,omitempty"`
+	// Prefer lists the licenses (or categories) to choose for dual licensed ("A OR B") packages, most preferred first,
+	// if Options.Prefer is not set; the packages then have the chosen license instead of the expression
+	Prefer []string `yaml:"prefer,omitempty"`
+	// Warn lists the licenses that are reported as a warning
+	Warn []string `yaml:"warn,omitempty"`
+	// FailUnknown reports packages whose license could not be determined, like Options.FailUnknown
+	FailUnknown bool `yaml:"failUnknown,omitempty"`
+	// IndexFallback looks up the license of modules whose license could not be detected, like Options.IndexFallback
+	IndexFallback bool `yaml:"indexFallback,omitempty"`
+	// Ignore and Exclude list import path patterns of packages that are not checked, or left out of the scan and the
+	// report altogether, in addition to Options.Ignore and Options.Exclude
+	Ignore  []string `yaml:"ignore,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Overrides maps import path prefixes to licenses, like Options.Overrides (which take precedence)
+	Overrides map[string]string `yaml:"overrides,omitempty"`
+	// AcceptUnknown lists the packages whose license could not be determined, but which are accepted anyway
+	AcceptUnknown []ImportPath `yaml:"acceptUnknown,omitempty"`
+	// AllowURLs and DenyURLs list license URL prefixes that are allowed or denied, regardless of the license ID
+	AllowURLs []string `yaml:"allowURLs,omitempty"`
+	DenyURLs  []string `yaml:"denyURLs,omitempty"`
+	// Reviewed lists the licenses that were determined or approved by a human
+	Reviewed []Review `yaml:"reviewed,omitempty"`
+	// Incompatible is the compatibility matrix: for the license of an importing package, the licenses its imports
+	// can't have; replaces DefaultIncompatible
+	Incompatible map[string][]string `yaml:"incompatible,omitempty"`
+	// AcceptExceptions lists license exceptions that make a denied license acceptable, like Options.AcceptExceptions
+	AcceptExceptions []string `yaml:"acceptExceptions,omitempty"`
+	// Severity maps issue kinds (eg. unknown-license) to their severity: error, warning or off
+	Severity map[IssueKind]Severity `yaml:"severity,omitempty"`
+	// Plugins lists the import path patterns of the packages that are built as plugins (-buildmode=plugin) and loaded
+	// at run time; the packages that only they use have ScopePlugin
+	Plugins []string `yaml:"plugins,omitempty"`
+	// Scopes has the policy for the packages of a scope (build, test, tool or plugin), replacing Allow, Deny and Warn
+	Scopes map[string]ScopePolicy `yaml:"scopes,omitempty"`
+	// Alternatives maps module paths to modules (optionally path@version) that can replace them, for -remediate
+	Alternatives map[string][]string `yaml:"alternatives,omitempty"`
+	// Licenses are custom licenses to recognize besides licensecheck's, like internal proprietary licenses
+	Licenses []CustomLicense `yaml:"licenses,omitempty"`
+	// Rules are custom policy rules, evaluated with each package after the other checks
+	Rules []Rule `yaml:"rules,omitempty"`
+}
+
+// Review records that a human approved the license of a package (or packages)
+type Review struct {
+	Package  string `yaml:"package" json:"package"` // import path; a "/..." suffix matches all packages below it
+	License  string `yaml:"license" json:"license"` // approved license
+	Reviewer string `yaml:"reviewer,omitempty" json:"reviewer,omitempty"`
+	Date     string `yaml:"date,omitempty" json:"date,omitempty"`
+	Note     string `yaml:"note,omitempty" json:"note,omitempty"`
+}
+
+// LoadConfig reads the configuration file; a missing file is not an error unless it's required
+func LoadConfig(file string, required bool) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return config, nil
+		}
+		return config, errors.Wrapf(err, "reading config %s", file)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, errors.Wrapf(err, "parsing config %s", file)
+	}
+	if err := config.validateSeverities(); err != nil {
+		return config, errors.Wrapf(err, "config %s", file)
+	}
+	if err := config.validateScopes(); err != nil {
+		return config, errors.Wrapf(err, "config %s", file)
+	}
+	if err := config.parseRules(); err != nil {
+		return config, errors.Wrapf(err, "config %s", file)
+	}
+	if err := validateOverrides(config.Overrides); err != nil {
+		return config, errors.Wrapf(err, "config %s", file)
+	}
+	return config, errors.Wrapf(config.loadCustomLicenses(file), "config %s", file)
+}
+
+func (c *Config) isAllowed(lic, url string) bool {
+	if matchURL(c.AllowURLs, url) {
+		return true
+	}
+	if matchURL(c.DenyURLs, url) {
+		return false
+	}
+	if len(c.Allow) == 0 {
+		return true
+	}
+	return licenseSatisfied(lic, func(lic string) bool {
+		for _, allowed := range c.Allow {
+			if matchLicense(lic, allowed) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matchURL reports whether the URL starts with one of the prefixes, ignoring the scheme
+func matchURL(prefixes []string, url string) bool {
+	if url == "" {
+		return false
+	}
+	url = trimScheme(url)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(url, trimScheme(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func trimScheme(url string) string {
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		return rest
+	}
+	return url
+}
+
+// isWarned reports whether every choice of licenses in the expression includes a license of the Warn list
+func (c *Config) isWarned(lic string) bool {
+	if len(c.Warn) == 0 || lic == UnknownLicense {
+		return false
+	}
+	return !licenseSatisfied(lic, func(lic string) bool {
+		for _, warned := range c.Warn {
+			if matchLicense(lic, warned) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (c *Config) isAcceptedUnknown(importPath ImportPath) bool {
+	for _, accepted := range c.AcceptUnknown {
+		if importPath == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// findReview returns the review of the package, if any; the most specific pattern wins
+func (c *Config) findReview(importPath ImportPath) *Review {
+	var best *Review
+	for i, r := range c.Reviewed {
+		if matchPattern(r.Package, importPath) && (best == nil || len(r.Package) > len(best.Package)) {
+			best = &c.Reviewed[i]
+		}
+	}
+	return best
+}
+
+// matchPattern reports whether the import path matches the pattern, which is an import path optionally ending in
+// "/..." to also match all packages below it
+func matchPattern(pattern string, importPath ImportPath) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+		return string(importPath) == prefix || strings.HasPrefix(string(importPath), prefix+"/")
+	}
+	return string(importPath) == pattern
+}
+
+// WriteStarterConfig writes a configuration that allows all licenses and accepts all unknowns currently in the report
+func WriteStarterConfig(file string, report *ModuleReport, force bool) error {
+	if _, err := os.Stat(file); err == nil && !force {
+		return errors.Errorf("%s already exists; use -force to overwrite it", file)
+	}
+
+	licenses := map[string]bool{}
+	var starter Config
+	for _, pr := range report.Packages {
+		if pr.License == UnknownLicense {
+			starter.AcceptUnknown = append(starter.AcceptUnknown, pr.ImportPath)
+		} else {
+			for _, lic := range licenseTerms(pr.License) {
+				licenses[lic] = true
+			}
+		}
+	}
+	starter.Allow = sortedKeys(licenses)
+	sort.Slice(starter.AcceptUnknown, func(i, j int) bool { return starter.AcceptUnknown[i] < starter.AcceptUnknown[j] })
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by golicenseguard -init from the licenses currently in use; tighten as needed.\n")
+	buf.WriteString("# allow: licenses that are allowed; packages with any other license are reported\n")
+	buf.WriteString("# acceptUnknown: packages without a detectable license that are accepted anyway\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(starter); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
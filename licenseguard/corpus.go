@@ -0,0 +1,145 @@
+package licenseguard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/licensecheck"
+	"github.com/pkg/errors"
+)
+
+// CustomLicense is a license that licensecheck doesn't know, like an internal proprietary license or a vendor EULA,
+// which is recognized in license files and source headers in addition to the built-in licenses
+type CustomLicense struct {
+	ID       string          `yaml:"id"`                 // SPDX-style ID, like LicenseRef-Acme-EULA or Proprietary-Acme-1.0
+	Text     string          `yaml:"text,omitempty"`     // license text, in licensecheck's license regular expression syntax
+	File     string          `yaml:"file,omitempty"`     // file with the text instead, relative to the config file
+	URL      string          `yaml:"url,omitempty"`      // URL that identifies the license, if any
+	Category LicenseCategory `yaml:"category,omitempty"` // category of the license; unknown if not set
+}
+
+// licenseCorpus is the licensecheck scanner with the built-in and custom licenses
+type licenseCorpus struct {
+	scanner *licensecheck.Scanner
+	id      string // hash of the custom licenses; part of the cache keys
+}
+
+var (
+	corpusMu       sync.Mutex
+	corpusScanners = map[string]*licenseCorpus{} // compiled scanners by id, since compiling is slow
+
+	// customCategories are the categories of the custom licenses of all the compiled scanners, for Category; a
+	// custom license is only detected by the scans that have it, so they can share them
+	customCategories atomic.Pointer[map[string]LicenseCategory]
+)
+
+// loadCustomLicenses reads the text files of the custom licenses in the config file, and checks that they compile
+func (c *Config) loadCustomLicenses(configFile string) error {
+	for i, l := range c.Licenses {
+		if l.ID == "" {
+			return errors.Errorf("license %d has no id", i+1)
+		}
+		if l.Category != "" && !isLicenseCategory(string(l.Category)) {
+			return errors.Errorf("license %s: unknown category %q", l.ID, l.Category)
+		}
+		if l.File != "" {
+			if l.Text != "" {
+				return errors.Errorf("license %s has both a text and a file", l.ID)
+			}
+			file := l.File
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(configFile), file)
+			}
+			text, err := os.ReadFile(file)
+			if err != nil {
+				return errors.Wrapf(err, "license %s", l.ID)
+			}
+			c.Licenses[i].Text = string(text)
+		}
+		if strings.TrimSpace(c.Licenses[i].Text) == "" && l.URL == "" {
+			return errors.Errorf("license %s has no text or url", l.ID)
+		}
+	}
+	_, err := c.customCorpus()
+	return err
+}
+
+// useLicenses makes the scan recognize the custom licenses of the config, besides the built-in ones
+func (o *Options) useLicenses() error {
+	lc, err := o.Config.customCorpus()
+	if err != nil {
+		return err
+	}
+	o.corpus = lc
+	return nil
+}
+
+// customCorpus returns the scanner with the custom licenses, or nil if there are none
+func (c *Config) customCorpus() (*licenseCorpus, error) {
+	if len(c.Licenses) == 0 {
+		return nil, nil
+	}
+	h := sha256.New()
+	for _, l := range c.Licenses {
+		h.Write([]byte(l.ID + "\x00" + l.Text + "\x00" + l.URL + "\x00" + string(l.Category) + "\x00"))
+	}
+	id := hex.EncodeToString(h.Sum(nil)[:8])
+
+	corpusMu.Lock()
+	defer corpusMu.Unlock()
+	if lc := corpusScanners[id]; lc != nil {
+		return lc, nil
+	}
+	licenses := licensecheck.BuiltinLicenses()
+	categories := map[string]LicenseCategory{}
+	for _, l := range c.Licenses {
+		licenses = append(licenses, licensecheck.License{ID: l.ID, LRE: l.Text, URL: l.URL})
+		if l.Category != "" {
+			categories[l.ID] = LicenseCategory(strings.ToLower(string(l.Category)))
+		}
+	}
+	scanner, err := licensecheck.NewScanner(licenses)
+	if err != nil {
+		return nil, errors.Wrap(err, "compiling the custom licenses")
+	}
+	lc := &licenseCorpus{scanner: scanner, id: id}
+	corpusScanners[id] = lc
+	merged := map[string]LicenseCategory{}
+	if old := customCategories.Load(); old != nil {
+		maps.Copy(merged, *old)
+	}
+	maps.Copy(merged, categories)
+	customCategories.Store(&merged)
+	return lc, nil
+}
+
+// scanLicenseText runs licensecheck on the text, with the custom licenses of lc if not nil
+func scanLicenseText(text []byte, lc *licenseCorpus) licensecheck.Coverage {
+	if lc != nil {
+		return lc.scanner.Scan(text)
+	}
+	return licensecheck.Scan(text)
+}
+
+// corpusId identifies the licenses that are recognized, since the cached licenses depend on them
+func corpusId(lc *licenseCorpus) string {
+	if lc != nil {
+		return lc.id
+	}
+	return ""
+}
+
+// customCategory returns the configured category of a custom license, if any
+func customCategory(lic string) (LicenseCategory, bool) {
+	if categories := customCategories.Load(); categories != nil {
+		category, ok := (*categories)[lic]
+		return category, ok
+	}
+	return "", false
+}
@@ -0,0 +1,214 @@
+package licenseguard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// depsDevURL is the deps.dev API; pkg.go.dev has no API, so this is used for the recorded license of a module version
+const depsDevURL = "https://api.deps.dev/v3/systems/go/packages/"
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+var (
+	indexLicenseCache   map[string][]string      // module@version -> licenses from deps.dev
+	indexLookups        map[string]chan struct{} // lookups in progress, closed when done
+	indexLicenseCacheMu sync.Mutex               // the index fallback looks up licenses concurrently
+)
+
+func indexLicenseCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "golicenseguard", "index-licenses.json")
+}
+
+func loadIndexLicenseCache() {
+	indexLicenseCache = map[string][]string{}
+	if file := indexLicenseCacheFile(); file != "" {
+		if data, err := os.ReadFile(file); err == nil {
+			json.Unmarshal(data, &indexLicenseCache)
+		}
+	}
+}
+
+func saveIndexLicenseCache() error {
+	indexLicenseCacheMu.Lock()
+	defer indexLicenseCacheMu.Unlock()
+	file := indexLicenseCacheFile()
+	if file == "" || indexLicenseCache == nil {
+		return nil
+	}
+	data, err := json.Marshal(indexLicenseCache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// fetchIndexLicenses returns the licenses deps.dev has recorded for the module version
+func fetchIndexLicenses(modulePath, version string) ([]string, error) {
+	indexLicenseCacheMu.Lock()
+	if indexLicenseCache == nil {
+		loadIndexLicenseCache()
+	}
+	key := modulePath + "@" + version
+	if licenses, ok := indexLicenseCache[key]; ok {
+		indexLicenseCacheMu.Unlock()
+		return licenses, nil
+	}
+	if lookup, ok := indexLookups[key]; ok {
+		// Another package of the same module is being looked up; wait for its result
+		indexLicenseCacheMu.Unlock()
+		<-lookup
+		return fetchIndexLicenses(modulePath, version)
+	}
+	if indexLookups == nil {
+		indexLookups = map[string]chan struct{}{}
+	}
+	lookup := make(chan struct{})
+	indexLookups[key] = lookup
+	indexLicenseCacheMu.Unlock()
+	defer func() {
+		indexLicenseCacheMu.Lock()
+		delete(indexLookups, key)
+		indexLicenseCacheMu.Unlock()
+		close(lookup)
+	}()
+
+	resp, err := httpClient.Get(depsDevURL + url.PathEscape(modulePath) + "/versions/" + url.PathEscape(version))
+	if err != nil {
+		return nil, errors.Wrapf(err, "looking up license of %s", key)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		setIndexLicenses(key, nil)
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("looking up license of %s: %s", key, resp.Status)
+	}
+	var body struct {
+		Licenses []string `json:"licenses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(err, "decoding license of %s", key)
+	}
+	setIndexLicenses(key, body.Licenses)
+	return body.Licenses, nil
+}
+
+func setIndexLicenses(key string, licenses []string) {
+	indexLicenseCacheMu.Lock()
+	defer indexLicenseCacheMu.Unlock()
+	indexLicenseCache[key] = licenses
+}
+
+// indexLicense returns the license deps.dev has recorded for the package's module version, as an SPDX expression, or
+// "" if it has none
+func (p *Package) indexLicense() (string, error) {
+	m := p.Module
+	if m == nil {
+		return "", nil
+	}
+	if m.Replace != nil {
+		m = m.Replace
+	}
+	if m.Version == "" {
+		return "", nil // main module, or replaced by a directory
+	}
+	licenses, err := fetchIndexLicenses(m.Path, m.Version)
+	if err != nil {
+		return "", err
+	}
+	var terms []string
+	for _, lic := range licenses {
+		if lic == "non-standard" {
+			return "", nil
+		}
+		if strings.Contains(lic, " OR ") && len(licenses) > 1 {
+			lic = "(" + lic + ")"
+		}
+		terms = append(terms, lic)
+	}
+	return strings.Join(terms, " AND "), nil
+}
+
+var spdxIdRegex = regexp.MustCompile(`[A-Za-z0-9.+-]+`)
+
+// canonicalLicenseId maps equivalent (eg. deprecated and current) SPDX IDs to the same string
+func canonicalLicenseId(id string) string {
+	id = strings.TrimSuffix(id, "-only")
+	if strings.HasSuffix(id, "+") {
+		id = strings.TrimSuffix(id, "+") + "-or-later"
+	}
+	return strings.ToLower(id)
+}
+
+// licenseIds returns the canonical IDs in the license expressions, ignoring operators
+func licenseIds(exprs ...string) map[string]bool {
+	ids := map[string]bool{}
+	for _, expr := range exprs {
+		for _, id := range spdxIdRegex.FindAllString(expr, -1) {
+			switch id {
+			case "AND", "OR", "WITH":
+				continue
+			}
+			ids[canonicalLicenseId(id)] = true
+		}
+	}
+	return ids
+}
+
+// licensesAgree reports whether all IDs in our license expression also appear in the index's licenses
+func licensesAgree(ours string, index []string) bool {
+	indexIds := licenseIds(index...)
+	for id := range licenseIds(ours) {
+		if !indexIds[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// crossCheck compares the detected licenses with the ones recorded by the package index
+func crossCheck(packages []PackageReport) ([]Issue, error) {
+	var issues []Issue
+	seen := map[string]bool{}
+	for _, pr := range packages {
+		if pr.Module == "" || pr.Version == "" || pr.License == UnknownLicense {
+			continue
+		}
+		key := pr.Module + "@" + pr.Version + " " + pr.License
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		index, err := fetchIndexLicenses(pr.Module, pr.Version)
+		if err != nil {
+			return issues, err
+		}
+		if len(index) == 0 || index[0] == "non-standard" {
+			continue // nothing to compare with
+		}
+		if !licensesAgree(pr.License, index) {
+			issues = append(issues, Issue{Kind: IssueLicenseMismatch, ImportPath: pr.ImportPath, License: pr.License,
+				Message: fmt.Sprintf("%s@%s is %s according to deps.dev", pr.Module, pr.Version, strings.Join(index, ", "))})
+		}
+	}
+	return issues, saveIndexLicenseCache()
+}
@@ -0,0 +1,36 @@
+package licenseguard
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// csvHeader is the first row written by WriteCsv
+var csvHeader = []string{"importPath", "module", "version", "license", "licenseFile", "violation", "ignored"}
+
+// WriteCsv writes the report as CSV (RFC 4180), with a row per non-standard package. A package violates the policy
+// if there's an issue for it, or if it's one of the denied imports of another package.
+func WriteCsv(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	seen := map[ImportPath]bool{}
+	for _, m := range report.Modules {
+		violations := map[ImportPath]bool{}
+		for _, issue := range m.Issues {
+			violations[issue.ImportPath] = true
+			for _, dep := range issue.Imports {
+				violations[dep.ImportPath] = true
+			}
+		}
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			if seen[pr.ImportPath] {
+				continue // same package in more than one module
+			}
+			seen[pr.ImportPath] = true
+			cw.Write([]string{string(pr.ImportPath), pr.Module, pr.Version, pr.License, pr.LicenseFile, strconv.FormatBool(violations[pr.ImportPath]), strconv.FormatBool(pr.Ignored)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
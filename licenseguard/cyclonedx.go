@@ -0,0 +1,140 @@
+package licenseguard
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CycloneDX 1.5 JSON BOM: one component per non-standard Go package, with the detected license and, for licenses
+// detected in a license file, the file as evidence and the licensecheck match confidence as properties.
+
+type cdxBom struct {
+	BomFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string   `json:"timestamp"`
+	Tools     cdxTools `json:"tools"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BomRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	Purl       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Evidence   *cdxEvidence  `json:"evidence,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+// cdxLicense is a CycloneDX license choice: either a single license or an SPDX expression
+type cdxLicense struct {
+	License    *cdxLicenseId `json:"license,omitempty"`
+	Expression string        `json:"expression,omitempty"`
+}
+
+type cdxLicenseId struct {
+	Id string `json:"id"`
+}
+
+type cdxEvidence struct {
+	Licenses    []cdxLicense    `json:"licenses,omitempty"`
+	Occurrences []cdxOccurrence `json:"occurrences,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxOccurrence struct {
+	Location string `json:"location"`
+}
+
+// cdxLicenses converts a license expression to a CycloneDX license choice; Unknown licenses have none
+func cdxLicenses(lic string) []cdxLicense {
+	if lic == "" || lic == UnknownLicense {
+		return nil
+	}
+	if strings.ContainsAny(lic, " ()") {
+		return []cdxLicense{{Expression: lic}}
+	}
+	return []cdxLicense{{License: &cdxLicenseId{Id: lic}}}
+}
+
+// cdxEvidenceOf returns the license evidence of a package whose license was read from a license file, and the
+// match confidence of each license in it as properties (CycloneDX has no confidence for license evidence)
+func cdxEvidenceOf(pr PackageReport) (*cdxEvidence, []cdxProperty) {
+	if pr.LicenseFile == "" {
+		return nil, nil
+	}
+	ev := &cdxEvidence{Licenses: cdxLicenses(pr.License), Occurrences: []cdxOccurrence{{Location: pr.LicenseFile}}}
+	ids := make([]string, 0, len(pr.Confidence))
+	for id := range pr.Confidence {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var props []cdxProperty
+	for _, id := range ids {
+		props = append(props, cdxProperty{Name: "golicenseguard:confidence:" + id, Value: fmt.Sprintf("%d%%", pr.Confidence[id])})
+	}
+	return ev, props
+}
+
+// newCycloneDXBom converts the report to a CycloneDX BOM
+func newCycloneDXBom(report Report, toolVersion string, created time.Time) cdxBom {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant
+	bom := cdxBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "golicenseguard", Version: toolVersion}}},
+		},
+		Components: []cdxComponent{},
+	}
+	seen := map[ImportPath]bool{}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			if seen[pr.ImportPath] {
+				continue // same package in more than one module
+			}
+			seen[pr.ImportPath] = true
+			c := cdxComponent{Type: "library", BomRef: string(pr.ImportPath), Name: string(pr.ImportPath), Version: pr.Version,
+				Licenses: cdxLicenses(pr.License)}
+			c.Evidence, c.Properties = cdxEvidenceOf(pr)
+			if _, refs := spdxDownload(pr); len(refs) > 0 {
+				c.Purl = refs[0].ReferenceLocator
+			}
+			bom.Components = append(bom.Components, c)
+		}
+	}
+	return bom
+}
+
+// WriteCycloneDX writes the report as a CycloneDX 1.5 JSON BOM, created by the given version of golicenseguard
+func WriteCycloneDX(w io.Writer, report Report, toolVersion string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newCycloneDXBom(report, toolVersion, time.Now()))
+}
@@ -0,0 +1,39 @@
+package licenseguard
+
+import (
+	"math"
+	"os"
+	"sort"
+
+	"github.com/google/licensecheck"
+)
+
+// logLicenseFile logs the license that was detected in the license file, with the confidence of each license and
+// whether it came from the cache; with LogMatches, also the raw licensecheck matches
+func (p *Package) logLicenseFile(o *Options, licenseFile string, match licenseMatch, err error) {
+	if err != nil {
+		o.Log.Info("license file", "package", p.ImportPath, "file", licenseFile, "err", err)
+	} else {
+		o.Log.Info("license file", "package", p.ImportPath, "file", licenseFile, "license", match.ID, "confidence", match.Confidence,
+			"cached", match.cached)
+	}
+	if o.LogMatches {
+		logLicensecheckMatches(o, string(p.ImportPath), licenseFile)
+	}
+}
+
+// logLicensecheckMatches logs every match that licensecheck finds in the file, including the ones that are ignored
+func logLicensecheckMatches(o *Options, importPath, file string) {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return // already reported
+	}
+	cov := scanLicenseText(text, o.corpus)
+	o.Log.Debug("licensecheck coverage", "package", importPath, "file", file, "percent", math.Round(cov.Percent*10)/10)
+	matches := append([]licensecheck.Match{}, cov.Match...)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	for _, m := range matches {
+		o.Log.Debug("licensecheck match", "package", importPath, "file", file, "license", m.ID, "type", m.Type, "start", m.Start,
+			"end", m.End, "url", m.IsURL, "confidence", matchConfidence(text, cov.Match, m))
+	}
+}
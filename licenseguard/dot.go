@@ -0,0 +1,61 @@
+package licenseguard
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// categoryColors are the Graphviz fill colors of the license categories, from green (permissive) to red (copyleft)
+var categoryColors = map[LicenseCategory]string{
+	CategoryPublicDomain:    "palegreen",
+	CategoryPermissive:      "lightgreen",
+	CategoryWeakCopyleft:    "khaki",
+	CategoryStrongCopyleft:  "orange",
+	CategoryNetworkCopyleft: "tomato",
+	CategoryProprietary:     "orchid",
+	CategoryUnknown:         "lightgray",
+}
+
+// expressionColor returns the color of the most restrictive category of the licenses in the expression
+func expressionColor(lic string) string {
+	worst := 0
+	for _, term := range licenseTerms(lic) {
+		worst = max(worst, slices.Index(categoryOrder, Category(term)))
+	}
+	if len(licenseTerms(lic)) == 0 {
+		worst = len(categoryOrder) - 1
+	}
+	return categoryColors[categoryOrder[worst]]
+}
+
+// WriteDot writes the import graph of the report (which needs Options.Graph) in Graphviz DOT format, with a node per
+// package labeled and colored by its license, and a legend of the license categories
+func WriteDot(w io.Writer, report Report) {
+	fmt.Fprintln(w, "digraph licenses {")
+	fmt.Fprintln(w, "  node [shape=box, style=filled];")
+	seen := map[ImportPath]bool{}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			if seen[pr.ImportPath] {
+				continue // same package in more than one module
+			}
+			seen[pr.ImportPath] = true
+			label := string(pr.ImportPath) + "\n" + pr.License
+			fmt.Fprintf(w, "  %s [label=%s, fillcolor=%s];\n", strconv.Quote(string(pr.ImportPath)), strconv.Quote(label), expressionColor(pr.License))
+			for _, imp := range pr.Imports {
+				fmt.Fprintf(w, "  %s -> %s;\n", strconv.Quote(string(pr.ImportPath)), strconv.Quote(string(imp)))
+			}
+		}
+	}
+	fmt.Fprintln(w, "  subgraph cluster_legend {")
+	fmt.Fprintln(w, "    label=\"license categories\";")
+	for _, category := range categoryOrder {
+		name := strings.ReplaceAll(string(category), "-", " ")
+		fmt.Fprintf(w, "    %s [label=%s, fillcolor=%s];\n", strconv.Quote("legend "+string(category)), strconv.Quote(name), categoryColors[category])
+	}
+	fmt.Fprintln(w, "  }")
+	fmt.Fprintln(w, "}")
+}
@@ -0,0 +1,100 @@
+package licenseguard
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sourceModule returns the module whose files the package has: the replacement if any, or else the module itself
+func (p *Package) sourceModule() *Module {
+	if p.Module == nil {
+		return nil
+	}
+	if p.Module.Replace != nil {
+		return p.Module.Replace
+	}
+	return p.Module
+}
+
+// hasSources reports whether the package's directory exists
+func (p *Package) hasSources() bool {
+	if p.Dir == "" {
+		return false
+	}
+	_, err := os.Stat(p.Dir)
+	return err == nil
+}
+
+// downloadMissingSources downloads the modules of the packages whose directory is missing, like a module cache that
+// was partially cleaned or a -list-json from another machine, and sets the directories of the packages in the module
+// cache. Modules without a version (like local replacements) can't be downloaded, and neither can the ones that fail;
+// their packages keep their missing directories.
+func downloadMissingSources(ctx context.Context, deps []Package, o *Options) error {
+	var versions []string
+	for _, p := range deps {
+		if m := p.sourceModule(); !p.Standard && m != nil && m.Version != "" && !p.hasSources() {
+			versions = appendUnique(versions, m.Path+"@"+m.Version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	downloaded, err := downloadModules(ctx, versions)
+	if err != nil {
+		return err
+	}
+	for i := range deps {
+		p := &deps[i]
+		m := p.sourceModule()
+		if p.Standard || m == nil || m.Version == "" || p.hasSources() {
+			continue
+		}
+		d, ok := downloaded[m.Path+"@"+m.Version]
+		if !ok || d.Error != "" || d.Dir == "" {
+			continue
+		}
+		rel, ok := strings.CutPrefix(p.ImportPath, p.Module.Path)
+		if !ok || rel != "" && !strings.HasPrefix(rel, "/") {
+			continue
+		}
+		// the packages share the Module, so copy it before changing it
+		module := *p.Module
+		if module.Replace != nil {
+			replace := *module.Replace
+			replace.Dir = d.Dir
+			module.Replace = &replace
+		}
+		module.Dir = d.Dir
+		p.Module = &module
+		p.Dir = filepath.Join(d.Dir, filepath.FromSlash(rel))
+		if o.Log != nil {
+			o.Log.Info("downloaded module", "package", p.ImportPath, "module", d.Path, "version", d.Version, "dir", d.Dir)
+		}
+	}
+	return nil
+}
+
+// checkPackageDirs checks that the directories of the packages are on this machine, since the license files are read
+// from them
+func checkPackageDirs(deps []Package, o *Options) error {
+	for _, p := range deps {
+		if p.Standard {
+			continue // don't need the GOROOT
+		}
+		hint := ""
+		if o.NoDownload {
+			hint = "; -no-download is set"
+		}
+		if p.Dir == "" {
+			return errors.Errorf("package %s has no Dir%s", p.ImportPath, hint)
+		}
+		if _, err := os.Stat(p.Dir); err != nil {
+			return errors.Wrapf(err, "directory of package %s is not on this machine; the license files are read from it%s", p.ImportPath, hint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package licenseguard
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// findEmbeddedLicense returns the first license-looking file that is embedded with a //go:embed directive in one
+// of the files, or ErrNoLicense
+func findEmbeddedLicense(dir string, files []string) (string, error) {
+	for _, file := range files {
+		patterns, err := readEmbedPatterns(filepath.Join(dir, file))
+		if err != nil {
+			return "", err
+		}
+		for _, pattern := range patterns {
+			// embed patterns are relative to the directory of the source file and use forward slashes
+			matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+			for _, match := range matches {
+				if fi, err := os.Stat(match); err == nil && !fi.IsDir() && isLicenseFile(strings.ToLower(filepath.Base(match))) {
+					return match, nil
+				}
+			}
+		}
+	}
+	return "", ErrNoLicense
+}
+
+// readEmbedPatterns returns the patterns of all //go:embed directives in the file
+func readEmbedPatterns(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		args, ok := strings.CutPrefix(scanner.Text(), "//go:embed ")
+		if !ok {
+			continue
+		}
+		for _, arg := range strings.Fields(args) {
+			if unquoted, err := strconv.Unquote(arg); err == nil {
+				arg = unquoted
+			}
+			patterns = append(patterns, strings.TrimPrefix(arg, "all:"))
+		}
+	}
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,67 @@
+package licenseguard
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// isDirPattern reports whether the package pattern is a directory, like ./cmd/..., rather than an import path
+func isDirPattern(pattern string) bool {
+	return pattern == "." || pattern == ".." || strings.HasPrefix(pattern, "./") || strings.HasPrefix(pattern, "../") || filepath.IsAbs(pattern)
+}
+
+// matchesEntrypoint reports whether the package matches the entrypoint pattern: an import path pattern (see
+// importPatternRegexp), or a directory relative to dir, optionally ending in /... for the directories below it
+func (p *Package) matchesEntrypoint(pattern, dir string) bool {
+	if !isDirPattern(pattern) {
+		return matchesAny([]string{pattern}, normalizeImportPath(p.ImportPath))
+	}
+	if p.Dir == "" {
+		return false
+	}
+	root, recursive := strings.CutSuffix(filepath.ToSlash(pattern), "/...")
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(dir, root)
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	return p.Dir == root || recursive && isWithin(p.Dir, root)
+}
+
+// reachableFromEntrypoints returns the packages that the entrypoints import, directly or not, and the entrypoints
+// themselves, which are the listed packages (not test packages) that match one of the patterns. The other packages
+// are only used by tests, tools or other commands, so they are not part of what ships with the entrypoints. The
+// packages that are not entrypoints become dependencies only, so the import chains start at the entrypoints.
+func reachableFromEntrypoints(deps []Package, dir string, entrypoints []string) ([]Package, error) {
+	reachable := map[ImportPath]bool{}
+	entry := map[ImportPath]bool{}
+	for _, pattern := range entrypoints {
+		matched := false
+		for _, p := range deps {
+			if p.DepOnly || p.ForTest != "" || !p.matchesEntrypoint(pattern, dir) {
+				continue
+			}
+			matched = true
+			entry[normalizeImportPath(p.ImportPath)] = true
+			reachable[normalizeImportPath(p.ImportPath)] = true
+			for _, d := range p.Deps {
+				reachable[normalizeImportPath(d)] = true
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("entrypoint %s matches none of the listed packages", pattern)
+		}
+	}
+	var kept []Package
+	for _, p := range deps {
+		if importPath := normalizeImportPath(p.ImportPath); reachable[importPath] {
+			p.DepOnly = !entry[importPath]
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
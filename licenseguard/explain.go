@@ -0,0 +1,262 @@
+package licenseguard
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/licensecheck"
+)
+
+// Explanation is everything that determined the verdict of a single package (-explain)
+type Explanation struct {
+	Target  string         `json:"target"`            // import path that was asked about
+	Package *PackageReport `json:"package,omitempty"` // nil if the listed packages don't use the target
+	// Matches are the licensecheck matches in the license file, including the ones that were ignored
+	Matches []ExplainMatch `json:"matches,omitempty"`
+	Cached  []string       `json:"cached,omitempty"` // caches the license was read from instead of detecting it
+	Rules   []string       `json:"rules,omitempty"`  // policy rules that apply to the package and its license
+	// Chains are the shortest import chains from the listed packages to the target, one through each importer
+	Chains   [][]ImportPath `json:"chains,omitempty"`
+	Issues   []Issue        `json:"issues,omitempty"`   // violations of the package or with it as an import
+	Warnings []Issue        `json:"warnings,omitempty"` // warnings of the package or with it as an import
+}
+
+// ExplainMatch is a licensecheck match in a license file
+type ExplainMatch struct {
+	ID         string `json:"id"`
+	Start      int    `json:"start"` // byte offset in the file
+	End        int    `json:"end"`
+	Confidence int    `json:"confidence"` // see matchConfidence
+	URL        bool   `json:"url,omitempty"`
+}
+
+// explain returns the explanation of the target package, after the report is complete; o has the policy of the
+// package's scope
+func explain(target string, report *ModuleReport, byImportPath map[ImportPath]*Package, importOf map[ImportPath][]ImportPath,
+	roots map[ImportPath]bool, scope Scope, o *Options) *Explanation {
+	importPath := normalizeImportPath(target)
+	e := &Explanation{Target: target}
+	for _, packages := range [][]PackageReport{report.Packages, report.MainPackages} {
+		for i := range packages {
+			if packages[i].ImportPath == importPath {
+				e.Package = &packages[i]
+			}
+		}
+	}
+	p := byImportPath[importPath]
+	if e.Package == nil || p == nil {
+		return e
+	}
+	if e.Package.LicenseFile != "" {
+		e.Matches = licenseFileMatches(e.Package.LicenseFile, o.corpus)
+	}
+	e.Cached = p.licenseCached
+	e.Rules = o.explainRules(importPath, e.Package, scope)
+	in := RuleInput{PackageReport: *e.Package, importOf: importOf}
+	for _, r := range o.Config.Rules {
+		if out, err := execute(r.when, in); err == nil && out != "" && out != "false" {
+			e.Rules = append(e.Rules, fmt.Sprintf("%s by the rule %s", r.Action, r.Name))
+		}
+	}
+
+	if roots[importPath] {
+		e.Chains = append(e.Chains, []ImportPath{importPath})
+	}
+	importers := slices.Clone(importOf[importPath])
+	slices.Sort(importers)
+	for _, importer := range slices.Compact(importers) {
+		if chain := importChain(importOf, roots, importer); chain != nil {
+			e.Chains = append(e.Chains, append(chain, importPath))
+		}
+	}
+
+	mentions := func(issue Issue) bool {
+		return issue.ImportPath == importPath || slices.ContainsFunc(issue.Imports, func(d Dependency) bool { return d.ImportPath == importPath })
+	}
+	for _, issue := range report.Issues {
+		if mentions(issue) {
+			e.Issues = append(e.Issues, issue)
+		}
+	}
+	for _, issue := range report.Warnings {
+		if mentions(issue) {
+			e.Warnings = append(e.Warnings, issue)
+		}
+	}
+	return e
+}
+
+// licenseFileMatches returns every match that licensecheck finds in the file, in the order of the file
+func licenseFileMatches(file string, lc *licenseCorpus) []ExplainMatch {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	cov := scanLicenseText(text, lc)
+	matches := append([]licensecheck.Match{}, cov.Match...)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	var explained []ExplainMatch
+	for _, m := range matches {
+		explained = append(explained, ExplainMatch{ID: m.ID, Start: m.Start, End: m.End, Confidence: matchConfidence(text, cov.Match, m), URL: m.IsURL})
+	}
+	return explained
+}
+
+// explainRules describes the rules of the policy that match the package or the licenses of its license expression
+func (o *Options) explainRules(importPath ImportPath, pr *PackageReport, scope Scope) []string {
+	var rules []string
+	for _, pattern := range slices.Concat(o.Ignore, o.Config.Ignore) {
+		if matchesAny([]string{pattern}, importPath) {
+			rules = append(rules, fmt.Sprintf("ignored by the pattern %s", pattern))
+		}
+	}
+	if pr.Source == LicenseSourceOverride {
+		rules = append(rules, "license from the overrides")
+	}
+	if pr.Review != nil {
+		rules = append(rules, fmt.Sprintf("reviewed as %s by %s on %s (reviewed %s)", pr.Review.License, pr.Review.Reviewer, pr.Review.Date, pr.Review.Package))
+	}
+	if pr.Error != "" {
+		if o.Config.isAcceptedUnknown(importPath) {
+			rules = append(rules, "unknown license accepted by acceptUnknown")
+		}
+		return rules
+	}
+
+	denyList := "the default deny list"
+	allowList, warnList := "allow", "warn"
+	deny := o.Deny
+	switch {
+	case len(deny) > 0:
+		denyList = "-deny"
+	case len(o.Config.Deny) > 0:
+		deny, denyList = o.Config.Deny, "deny"
+	default:
+		deny = DefaultDeny
+	}
+	for name, s := range scopeNames {
+		if policy, ok := o.Config.Scopes[name]; ok && s == scope {
+			rules = append(rules, fmt.Sprintf("policy of the %s scope", name))
+			if policy.Deny != nil {
+				denyList = "scopes." + name + ".deny"
+			}
+			if policy.Allow != nil {
+				allowList = "scopes." + name + ".allow"
+			}
+			if policy.Warn != nil {
+				warnList = "scopes." + name + ".warn"
+			}
+		}
+	}
+	if matchURL(o.Config.AllowURLs, pr.LicenseURL) {
+		rules = append(rules, fmt.Sprintf("license URL %s allowed by allowURLs", pr.LicenseURL))
+	} else if matchURL(o.Config.DenyURLs, pr.LicenseURL) {
+		rules = append(rules, fmt.Sprintf("license URL %s denied by denyURLs", pr.LicenseURL))
+	}
+	for _, lic := range licenseTerms(pr.License) {
+		if _, exception := splitException(lic); o.isAcceptedException(exception) {
+			rules = append(rules, fmt.Sprintf("%s: exception %s accepted by acceptExceptions", lic, exception))
+		}
+		for _, rule := range deny {
+			if matchLicenseTerm(lic, rule, true) {
+				rules = append(rules, fmt.Sprintf("%s: denied by %s in %s", lic, rule, denyList))
+			}
+		}
+		for _, rule := range o.Config.Allow {
+			if matchLicense(lic, rule) {
+				rules = append(rules, fmt.Sprintf("%s: allowed by %s in %s", lic, rule, allowList))
+			}
+		}
+		for _, rule := range o.Config.Warn {
+			if matchLicense(lic, rule) {
+				rules = append(rules, fmt.Sprintf("%s: warned by %s in %s", lic, rule, warnList))
+			}
+		}
+	}
+	if len(o.Config.Allow) > 0 && !o.Config.isAllowed(pr.License, pr.LicenseURL) {
+		rules = append(rules, fmt.Sprintf("%s is not in %s", pr.License, allowList))
+	}
+	return rules
+}
+
+// writeText writes the explanation as indented sections
+func (e *Explanation) writeText(out io.Writer) {
+	fmt.Fprintf(out, "# %s\n", e.Target)
+	pr := e.Package
+	if pr == nil {
+		fmt.Fprintf(out, "(%s is not used by the listed packages)\n", e.Target)
+		return
+	}
+	if pr.Module != "" {
+		fmt.Fprintf(out, "module: %s\n", strings.TrimSpace(pr.Module+" "+pr.Version))
+		if pr.Replace != "" {
+			fmt.Fprintf(out, "replaced by: %s\n", pr.Replace)
+		}
+	}
+	fmt.Fprintf(out, "dir: %s\n", pr.Dir)
+	if pr.Error != "" {
+		fmt.Fprintf(out, "license: %s (%s)\n", pr.License, pr.Error)
+	} else {
+		fmt.Fprintf(out, "license: %s (%s), from %s\n", pr.License, cmp.Or(pr.Category, string(CategoryUnknown)), pr.Source)
+	}
+	if pr.Expression != "" {
+		fmt.Fprintf(out, "  chosen from: %s\n", pr.Expression)
+	}
+	if pr.LicenseFile != "" {
+		fmt.Fprintf(out, "  license file: %s%s\n", pr.LicenseFile, formatConfidence(pr.Confidence))
+	}
+	if pr.LicenseURL != "" {
+		fmt.Fprintf(out, "  license URL: %s\n", pr.LicenseURL)
+	}
+	for _, m := range e.Matches {
+		url := ""
+		if m.URL {
+			url = ", by URL"
+		}
+		fmt.Fprintf(out, "  match: %s at bytes %d-%d, confidence %d%%%s\n", m.ID, m.Start, m.End, m.Confidence, url)
+	}
+	for _, file := range sortedKeys(pr.FileLicenses) {
+		fmt.Fprintf(out, "  header: %s: %s\n", file, pr.FileLicenses[file])
+	}
+	if pr.Conflict != "" {
+		fmt.Fprintf(out, "  conflict: %s\n", pr.Conflict)
+	}
+	for _, b := range pr.Bundled {
+		fmt.Fprintf(out, "  bundled: %s: %s\n", b.File, b.License)
+	}
+	for _, cached := range e.Cached {
+		fmt.Fprintf(out, "  cached: %s\n", cached)
+	}
+	fmt.Fprintf(out, "verdict: %s (%s scope)\n", pr.Verdict, cmp.Or(string(pr.Scope), "build"))
+	for _, rule := range e.Rules {
+		fmt.Fprintf(out, "  rule: %s\n", rule)
+	}
+	if len(e.Chains) == 0 {
+		fmt.Fprintln(out, "imported by: (none of the listed packages)")
+	} else {
+		fmt.Fprintln(out, "imported by:")
+	}
+	for _, chain := range e.Chains {
+		fmt.Fprintf(out, "  %s\n", joinImportPaths(chain, " -> "))
+	}
+	for _, issue := range e.Issues {
+		fmt.Fprintf(out, "issue: %s\n", issue.text())
+	}
+	for _, warning := range e.Warnings {
+		fmt.Fprintf(out, "warning: %s\n", warning.text())
+	}
+}
+
+// WriteExplain writes the -explain explanation of each module of the report
+func WriteExplain(w io.Writer, report Report) {
+	for _, m := range report.Modules {
+		if m.Explain != nil {
+			m.Explain.writeText(w)
+		}
+	}
+}
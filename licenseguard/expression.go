@@ -0,0 +1,251 @@
+package licenseguard
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// orLicenses combines distinct license IDs into an SPDX "A OR B" expression, sorted for determinism
+func orLicenses(ids []string) string {
+	ids = append([]string{}, ids...)
+	sort.Strings(ids)
+	return strings.Join(ids, " OR ")
+}
+
+// andLicenses combines distinct license expressions into an SPDX "A AND B" expression, sorted for determinism
+func andLicenses(exprs []string) string {
+	terms := make([]string, len(exprs))
+	for i, expr := range exprs {
+		if len(exprs) > 1 && strings.Contains(expr, " OR ") {
+			expr = "(" + expr + ")"
+		}
+		terms[i] = expr
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, " AND ")
+}
+
+// licenseSatisfied reports whether the SPDX license expression is satisfied when the licenses for which ok returns
+// true are acceptable: any branch of an OR, and all terms of an AND. A "WITH" exception is passed to ok along with
+// its license, eg. "GPL-2.0 WITH Classpath-exception-2.0". AND binds tighter than OR, as in SPDX.
+func licenseSatisfied(expr string, ok func(lic string) bool) bool {
+	p := exprParser{tokens: tokenizeLicense(expr), ok: ok}
+	return p.or()
+}
+
+// tokenizeLicense splits an SPDX expression into parentheses, AND, OR and licenses (including any WITH exception)
+func tokenizeLicense(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	var tokens []string
+	fields := strings.Fields(expr)
+	for i := 0; i < len(fields); i++ {
+		if strings.EqualFold(fields[i], "WITH") && len(tokens) > 0 && i+1 < len(fields) {
+			tokens[len(tokens)-1] += " WITH " + fields[i+1]
+			i++
+			continue
+		}
+		tokens = append(tokens, fields[i])
+	}
+	return tokens
+}
+
+type exprParser struct {
+	tokens []string
+	ok     func(lic string) bool
+}
+
+func (p *exprParser) peek(keyword string) bool {
+	return len(p.tokens) > 0 && strings.EqualFold(p.tokens[0], keyword)
+}
+
+func (p *exprParser) or() bool {
+	result := p.and()
+	for p.peek("OR") {
+		p.tokens = p.tokens[1:]
+		result = p.and() || result // always parse the right hand side
+	}
+	return result
+}
+
+func (p *exprParser) and() bool {
+	result := p.term()
+	for p.peek("AND") {
+		p.tokens = p.tokens[1:]
+		result = p.term() && result
+	}
+	return result
+}
+
+func (p *exprParser) term() bool {
+	if len(p.tokens) == 0 {
+		return false
+	}
+	token := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	if token == "(" {
+		result := p.or()
+		if p.peek(")") {
+			p.tokens = p.tokens[1:]
+		}
+		return result
+	}
+	return p.ok(token)
+}
+
+// orBranches splits an SPDX expression into its top level OR branches, without their outer parentheses
+func orBranches(expr string) []string {
+	var branches [][]string
+	var branch []string
+	depth := 0
+	for _, token := range tokenizeLicense(expr) {
+		switch {
+		case token == "(":
+			depth++
+		case token == ")":
+			depth--
+		case depth == 0 && strings.EqualFold(token, "OR"):
+			branches = append(branches, branch)
+			branch = nil
+			continue
+		}
+		branch = append(branch, token)
+	}
+	branches = append(branches, branch)
+
+	exprs := make([]string, len(branches))
+	for i, tokens := range branches {
+		if len(tokens) > 2 && tokens[0] == "(" && closingParen(tokens) == len(tokens)-1 {
+			tokens = tokens[1 : len(tokens)-1]
+		}
+		exprs[i] = strings.NewReplacer("( ", "(", " )", ")").Replace(strings.Join(tokens, " "))
+	}
+	return exprs
+}
+
+// closingParen returns the index of the parenthesis that closes the one that tokens start with, or -1
+func closingParen(tokens []string) int {
+	depth := 0
+	for i, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// chooseLicense returns the first branch of a dual licensed ("A OR B") expression that has a license of the prefer
+// list, trying the preferred licenses in order, or the expression itself if there is no such branch
+func chooseLicense(expr string, prefer []string) string {
+	branches := orBranches(expr)
+	if len(branches) < 2 {
+		return expr
+	}
+	for _, preferred := range prefer {
+		for _, branch := range branches {
+			for _, lic := range licenseTerms(branch) {
+				if matchLicense(lic, preferred) {
+					return branch
+				}
+			}
+		}
+	}
+	return expr
+}
+
+// licenseTerms returns the licenses (with any WITH exception) in the SPDX license expression
+func licenseTerms(expr string) []string {
+	var terms []string
+	for _, token := range tokenizeLicense(expr) {
+		switch strings.ToUpper(token) {
+		case "(", ")", "AND", "OR":
+		default:
+			terms = appendUnique(terms, token)
+		}
+	}
+	return terms
+}
+
+// versionedLicenseRegexp matches license IDs with a version and an optional variant, like GPL-2.0-only,
+// LGPL-2.1-or-later, GPL-3.0+ or Apache-2.0
+var versionedLicenseRegexp = regexp.MustCompile(`^(.+?)-(\d+(?:\.\d+)*)(-only|-or-later|\+)?$`)
+
+// parseLicenseVersion splits a license ID into its family (eg. GPL), version and whether it's the "-or-later" (or "+")
+// variant; an ID without a variant (the deprecated GPL-2.0) is that version only
+func parseLicenseVersion(id string) (family, version string, orLater, variant, ok bool) {
+	m := versionedLicenseRegexp.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", false, false, false
+	}
+	return m[1], m[2], m[3] == "-or-later" || m[3] == "+", m[3] != "", true
+}
+
+// compareLicenseVersions compares dotted license versions, like 2.0 and 2.1
+func compareLicenseVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// matchLicenseTerm reports whether a single license of an expression (with an optional WITH exception) matches a
+// policy rule: a category, or a license ID with an optional exception. A rule with an exception only matches licenses
+// with that exception; a rule without one matches regardless of the exception, since exceptions only add
+// permissions. Versioned IDs are compared by version: a rule without a variant, like GPL-2.0, matches every variant
+// of that version. Otherwise a license matches an allow (or warn) rule if it can be used under one of the versions of
+// the rule (GPL-2.0-or-later matches GPL-3.0-only), and a deny rule only if all of its versions are denied
+// (GPL-2.0-or-later doesn't match GPL-3.0-only). Deny rules that aren't versioned IDs, like GPL, also match any
+// license that contains them.
+func matchLicenseTerm(term, rule string, deny bool) bool {
+	if strings.TrimSpace(rule) == "" {
+		return false // an empty rule would be a substring of every license
+	}
+	id, exception := splitException(term)
+	ruleId, ruleException := splitException(rule)
+	if ruleException != "" && !strings.EqualFold(exception, ruleException) {
+		return false
+	}
+	if isLicenseCategory(ruleId) {
+		return strings.EqualFold(string(Category(id)), ruleId)
+	}
+	if strings.EqualFold(id, ruleId) {
+		return true
+	}
+	ruleFamily, ruleVersion, ruleOrLater, ruleVariant, ruleOk := parseLicenseVersion(ruleId)
+	family, version, orLater, _, ok := parseLicenseVersion(id)
+	if ruleOk && ok {
+		if !strings.EqualFold(family, ruleFamily) {
+			return false
+		}
+		c := compareLicenseVersions(version, ruleVersion)
+		switch {
+		case !ruleVariant:
+			return c == 0
+		case deny && orLater:
+			return ruleOrLater && c >= 0 // all later versions must be denied too
+		case ruleOrLater:
+			return c >= 0 || orLater
+		case orLater:
+			return c <= 0
+		default:
+			return c == 0
+		}
+	}
+	return deny && strings.Contains(id, ruleId)
+}
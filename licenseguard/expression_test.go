@@ -0,0 +1,72 @@
+package licenseguard
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLicenseTerms(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"MIT", []string{"MIT"}},
+		{"MIT OR Apache-2.0", []string{"MIT", "Apache-2.0"}},
+		{"(MIT OR Apache-2.0) AND BSD-3-Clause", []string{"MIT", "Apache-2.0", "BSD-3-Clause"}},
+		{"GPL-2.0-only WITH Classpath-exception-2.0 OR MIT", []string{"GPL-2.0-only WITH Classpath-exception-2.0", "MIT"}},
+		{"MIT AND MIT", []string{"MIT"}},
+	}
+	for _, tt := range tests {
+		if got := licenseTerms(tt.expr); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("licenseTerms(%q) = %q; want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLicenseTerm(t *testing.T) {
+	tests := []struct {
+		term, rule string
+		deny       bool
+		want       bool
+	}{
+		{"MIT", "MIT", false, true},
+		{"MIT", "mit", true, true},
+		{"MIT", "Apache-2.0", true, false},
+		// a rule without a variant matches all variants of the version
+		{"GPL-2.0-only", "GPL-2.0", true, true},
+		{"GPL-2.0-or-later", "GPL-2.0", true, true},
+		{"GPL-3.0-only", "GPL-2.0", true, false},
+		// allow rules match a license that can be used under one of their versions
+		{"GPL-3.0-only", "GPL-2.0-or-later", false, true},
+		{"GPL-2.0-or-later", "GPL-3.0-only", false, true},
+		{"GPL-2.0-only", "GPL-3.0-only", false, false},
+		{"GPL-3.0+", "GPL-2.0-or-later", false, true},
+		// deny rules only match if all the versions of the license are denied
+		{"GPL-2.0-or-later", "GPL-3.0-only", true, false},
+		{"GPL-3.0-or-later", "GPL-2.0-or-later", true, true},
+		{"GPL-2.0-or-later", "GPL-3.0-or-later", true, false},
+		{"LGPL-2.1-only", "GPL-2.1-only", true, false},
+		// exceptions
+		{"GPL-2.0-only WITH Classpath-exception-2.0", "GPL-2.0-only", true, true},
+		{"GPL-2.0-only", "GPL-2.0-only WITH Classpath-exception-2.0", false, false},
+		{"GPL-2.0-only WITH classpath-exception-2.0", "GPL-2.0-only WITH Classpath-exception-2.0", false, true},
+		{"GPL-2.0-only WITH GCC-exception-2.0", "GPL-2.0-only WITH Classpath-exception-2.0", false, false},
+		// deny rules that aren't versioned IDs match any license that contains them
+		{"AGPL-3.0-only", "AGPL", true, true},
+		{"AGPL-3.0-only", "AGPL", false, false},
+		// categories
+		{"SSPL-1.0", "network-copyleft", true, true},
+		{"AGPL-3.0-or-later", "Network-Copyleft", false, true},
+		{"MIT", "permissive", false, true},
+		{"MIT", "strong-copyleft", true, false},
+		// empty rules match nothing
+		{"MIT", "", true, false},
+		{"MIT", " ", false, false},
+		{"GPL-2.0-only WITH Classpath-exception-2.0", "", true, false},
+	}
+	for _, tt := range tests {
+		if got := matchLicenseTerm(tt.term, tt.rule, tt.deny); got != tt.want {
+			t.Errorf("matchLicenseTerm(%q, %q, deny %v) = %v; want %v", tt.term, tt.rule, tt.deny, got, tt.want)
+		}
+	}
+}
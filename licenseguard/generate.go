@@ -0,0 +1,111 @@
+package licenseguard
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GenerateOptions are the options of GenerateGoFile
+type GenerateOptions struct {
+	Package string // package name of the file; default the package of the other Go files in its directory
+	Texts   bool   // also write the license and NOTICE texts of the modules
+}
+
+// goString returns the text as a Go string literal: a raw string if it can be one, which is easier to read
+func goString(text string) string {
+	if strings.ContainsAny(text, "`\r") {
+		return strconv.Quote(text)
+	}
+	return "`" + text + "`"
+}
+
+// defaultPackageName returns the name of the package of the Go files in dir (other than file and tests), or else
+// the name of dir if it's an identifier, or else main
+func defaultPackageName(dir, file string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, match := range matches {
+		if filepath.Base(match) == filepath.Base(file) || strings.HasSuffix(match, "_test.go") {
+			continue
+		}
+		if f, err := parser.ParseFile(token.NewFileSet(), match, nil, parser.PackageClauseOnly); err == nil {
+			return f.Name.Name
+		}
+	}
+	if abs, err := filepath.Abs(dir); err == nil && token.IsIdentifier(filepath.Base(abs)) {
+		return filepath.Base(abs)
+	}
+	return "main"
+}
+
+// generateGo returns the Go source with the licenses (and texts) of the dependency modules of the report
+func generateGo(report Report, opts GenerateOptions) ([]byte, error) {
+	modules := collectNotices(report)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by golicenseguard; DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	fmt.Fprintln(&b, "// Licenses are the SPDX license expressions of the dependency modules, by path@version")
+	fmt.Fprintln(&b, "var Licenses = map[string]string{")
+	for _, mn := range modules {
+		sort.Strings(mn.licenses)
+		licenses := slices.Clone(mn.licenses)
+		for i, lic := range licenses {
+			if len(licenses) > 1 && strings.Contains(lic, " OR ") {
+				licenses[i] = "(" + lic + ")"
+			}
+		}
+		fmt.Fprintf(&b, "%q: %q,\n", mn.name, strings.Join(licenses, " AND "))
+	}
+	fmt.Fprintln(&b, "}")
+	if opts.Texts {
+		fmt.Fprintln(&b, "\n// LicenseTexts are the license and NOTICE files of the dependency modules, by path@version")
+		fmt.Fprintln(&b, "var LicenseTexts = map[string]string{")
+		for _, mn := range modules {
+			files := mn.licenseFiles
+			if len(files) == 0 && mn.dir != "" {
+				// The license is from the source headers; use the license text of the module, if it has one
+				if file, err := findLicenseFileCached(mn.dir); err == nil {
+					files = []string{file}
+				}
+			}
+			var texts []string
+			for _, file := range append(files, mn.noticeFiles...) {
+				text, err := readNoticeFile(file)
+				if err != nil {
+					return nil, err
+				}
+				texts = append(texts, string(bytes.TrimRight(text, "\n\r\t ")))
+			}
+			if len(texts) > 0 {
+				fmt.Fprintf(&b, "%q: %s,\n", mn.name, goString(strings.Join(texts, "\n\n")+"\n"))
+			}
+		}
+		fmt.Fprintln(&b, "}")
+	}
+	source, err := format.Source(b.Bytes())
+	return source, errors.Wrap(err, "formatting the generated source")
+}
+
+// GenerateGoFile writes a Go source file with a map of the dependency modules of the report to their licenses, and
+// optionally to their license texts, for applications that show them at runtime (eg. an "Open Source Licenses" page)
+func GenerateGoFile(file string, report Report, opts GenerateOptions) error {
+	if opts.Package == "" {
+		opts.Package = defaultPackageName(filepath.Dir(file), file)
+	} else if !token.IsIdentifier(opts.Package) {
+		return errors.Errorf("invalid package name %q", opts.Package)
+	}
+	source, err := generateGo(report, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, source, 0644)
+}
@@ -0,0 +1,74 @@
+package licenseguard
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GitHubCommentMarker is in the pull request comments written by WriteGitHubComment, so they can be updated
+const GitHubCommentMarker = "<!-- golicenseguard -->"
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// WriteGitHub writes the issues (as errors) and warnings of the report as GitHub Actions workflow commands, which
+// annotate the import (or the go.mod line of the module) in the pull request, at the same locations as WriteSarif
+func WriteGitHub(w io.Writer, report Report) error {
+	for _, m := range report.Modules {
+		if m.Error != "" {
+			fmt.Fprintf(w, "::error title=golicenseguard::%s\n", githubDataEscaper.Replace(m.Error))
+		}
+		packages := map[ImportPath]PackageReport{}
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			packages[pr.ImportPath] = pr
+		}
+		for _, issue := range m.Issues {
+			for _, result := range sarifResults(m, issue, "error", packages) {
+				writeGitHubCommand(w, result)
+			}
+		}
+		for _, issue := range m.Warnings {
+			for _, result := range sarifResults(m, issue, "warning", packages) {
+				writeGitHubCommand(w, result)
+			}
+		}
+	}
+	return nil
+}
+
+func writeGitHubCommand(w io.Writer, result sarifResult) {
+	props := "title=" + githubPropertyEscaper.Replace(strings.ReplaceAll(result.RuleId, "-", " "))
+	if len(result.Locations) > 0 {
+		loc := result.Locations[0].PhysicalLocation
+		props += ",file=" + githubPropertyEscaper.Replace(loc.ArtifactLocation.Uri)
+		if loc.Region != nil {
+			props += fmt.Sprintf(",line=%d", loc.Region.StartLine)
+		}
+	}
+	fmt.Fprintf(w, "::%s %s::%s\n", result.Level, props, githubDataEscaper.Replace(result.Message.Text))
+}
+
+// WriteGitHubComment writes a Markdown pull request comment with the violations and warnings of the report, starting
+// with GitHubCommentMarker
+func WriteGitHubComment(w io.Writer, report Report) {
+	fmt.Fprintln(w, GitHubCommentMarker)
+	fmt.Fprintln(w, "## License check")
+	violations := reportIssues(report, false)
+	switch len(violations) {
+	case 0:
+		fmt.Fprintln(w, "\nNo license violations found.")
+	case 1:
+		fmt.Fprintln(w, "\n1 license violation found.")
+	default:
+		fmt.Fprintf(w, "\n%d license violations found.\n", len(violations))
+	}
+	if len(violations) > 0 {
+		writeMarkdownIssues(w, "Violations", violations)
+	}
+	if warnings := reportIssues(report, true); len(warnings) > 0 {
+		writeMarkdownIssues(w, "Warnings", warnings)
+	}
+}
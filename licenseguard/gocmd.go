@@ -0,0 +1,71 @@
+package licenseguard
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type goCommandLogKey struct{}
+
+// withGoCommandLog returns a context for which the go commands are logged to Options.Log (at the debug level), with
+// Options.LogCommands
+func withGoCommandLog(ctx context.Context, o *Options) context.Context {
+	if o.Log == nil || !o.LogCommands {
+		return ctx
+	}
+	return context.WithValue(ctx, goCommandLogKey{}, o.Log)
+}
+
+// goCommand returns a go command to run in dir, with the extra environment variables env (eg. GOOS)
+func goCommand(ctx context.Context, dir string, env []string, args ...string) *exec.Cmd {
+	if log, ok := ctx.Value(goCommandLogKey{}).(*slog.Logger); ok {
+		command := append(append(append([]string{}, env...), "go"), args...)
+		log.Debug("running go command", "command", strings.Join(command, " "), "dir", dir)
+	}
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Env = goEnv(env)
+	return cmd
+}
+
+// runGo runs the go command and returns its output. If it fails, the error includes what the go command wrote to
+// stderr; if ctx expired, the cause of the error is the context's error.
+func runGo(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	cmd := goCommand(ctx, dir, env, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		name := "go " + strings.Join(args[:min(len(args), 2)], " ")
+		if ctx.Err() != nil {
+			return nil, errors.Wrap(ctx.Err(), name)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Errorf("%s: %v: %s", name, err, msg)
+		}
+		return nil, errors.Wrap(err, name)
+	}
+	return out, nil
+}
+
+// withTimeout returns a context that expires after the timeout (or never if the timeout is 0), or when ctx does
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutError replaces a deadline exceeded error with one that says which timeout was exceeded
+func timeoutError(err error, timeout time.Duration) error {
+	if errors.Cause(err) == context.DeadlineExceeded {
+		return errors.Errorf("go list did not finish within the timeout of %s", timeout)
+	}
+	return err
+}
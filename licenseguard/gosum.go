@@ -0,0 +1,57 @@
+package licenseguard
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+)
+
+// GoSumRoot is the import path of the package that imports the modules of a go.sum file in the report of ScanGoSum
+const GoSumRoot = "go.sum"
+
+// readGoSum returns the modules whose source is in the go.sum file (not just their go.mod), with the highest version
+// of each module; the other versions were needed for the module graph but aren't built
+func readGoSum(r io.Reader) (map[ImportPath]*Module, error) {
+	modules := map[ImportPath]*Module{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 || !strings.HasPrefix(fields[1], "v") {
+			return nil, errors.Errorf("line %d: not a go.sum line", line)
+		}
+		path, version := fields[0], fields[1]
+		if strings.HasSuffix(version, "/go.mod") {
+			continue
+		}
+		if m := modules[ImportPath(path)]; m == nil || semver.Compare(version, m.Version) > 0 {
+			modules[ImportPath(path)] = &Module{Path: path, Version: version}
+		}
+	}
+	return modules, scanner.Err()
+}
+
+// ScanGoSum checks the licenses of the modules in a go.sum file, like ScanBinary: each module is looked up in the
+// module cache (and downloaded if needed), and is reported as a package with the module path, imported by GoSumRoot
+func ScanGoSum(r io.Reader, opts Options) (*ModuleReport, error) {
+	o := &opts
+	all, err := readGoSum(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading go.sum")
+	}
+	modules := map[ImportPath]*Module{}
+	for importPath, m := range all {
+		if !o.isExcluded(importPath) {
+			modules[importPath] = m
+		}
+	}
+	ctx, cancel := withTimeout(withGoCommandLog(context.Background(), o), o.Timeout)
+	defer cancel()
+	return scanModules(ctx, GoSumRoot, GoSumRoot, &Module{Main: true}, modules, o)
+}
@@ -0,0 +1,82 @@
+package licenseguard
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	importPatterns   = map[string]*regexp.Regexp{}
+	importPatternsMu sync.Mutex
+)
+
+// importPatternRegexp converts an import path pattern to a regular expression: "..." (or "**") matches any string
+// (including slashes) as with go commands, so a trailing "/..." (or "/**") also matches the path itself; "*", "?" and
+// "[...]" are as in path.Match and don't match slashes
+func importPatternRegexp(pattern string) *regexp.Regexp {
+	importPatternsMu.Lock()
+	defer importPatternsMu.Unlock()
+	if re, ok := importPatterns[pattern]; ok {
+		return re
+	}
+	var sb strings.Builder
+	sb.WriteString("^")
+	rest, all := strings.CutSuffix(pattern, "/...")
+	if !all {
+		rest, all = strings.CutSuffix(pattern, "/**")
+	}
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case strings.HasPrefix(rest[i:], "..."):
+			sb.WriteString(".*")
+			i += 2
+		case strings.HasPrefix(rest[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(rest[i:], ']'); end > 0 {
+				sb.WriteString(rest[i : i+end+1])
+				i += end
+				break
+			}
+			fallthrough
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if all {
+		sb.WriteString("(/.*)?")
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$") // bad character class; match literally
+	}
+	importPatterns[pattern] = re
+	return re
+}
+
+// matchesAny reports whether the package matches one of the import path patterns
+func matchesAny(patterns []string, importPath ImportPath) bool {
+	for _, pattern := range patterns {
+		if importPatternRegexp(pattern).MatchString(string(importPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored reports whether the package matches one of the Ignore patterns, of the options or the configuration
+func (o *Options) isIgnored(importPath ImportPath) bool {
+	return matchesAny(o.Ignore, importPath) || matchesAny(o.Config.Ignore, importPath)
+}
+
+// isExcluded reports whether the package matches one of the Exclude patterns, of the options or the configuration
+func (o *Options) isExcluded(importPath ImportPath) bool {
+	return matchesAny(o.Exclude, importPath) || matchesAny(o.Config.Exclude, importPath)
+}
@@ -0,0 +1,430 @@
+package licenseguard
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Media types of image manifests and indexes, in the Docker and OCI flavors
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var registryClient = &http.Client{Timeout: 10 * time.Minute}
+
+// imageManifest is an image manifest or an image index (manifest list)
+type imageManifest struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Layers []struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+	} `json:"layers"`
+}
+
+// imageRef is a parsed image reference, like ghcr.io/foo/bar:tag
+type imageRef struct {
+	registry, repository, reference string
+}
+
+// parseImageRef parses an image reference; images without a registry are on Docker Hub, and without a tag are :latest
+func parseImageRef(ref string) imageRef {
+	r := imageRef{registry: "registry-1.docker.io", reference: "latest"}
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, r.reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, r.reference = name[:i], name[i+1:]
+	}
+	if first, rest, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.registry, name = first, rest
+	}
+	if r.registry == "docker.io" || r.registry == "index.docker.io" {
+		r.registry = "registry-1.docker.io" // the API of Docker Hub is on another host than its name
+	}
+	if r.registry == "registry-1.docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	r.repository = name
+	return r
+}
+
+// registry fetches manifests and blobs of a repository, with an anonymous bearer token if the registry asks for one
+type registry struct {
+	ctx   context.Context
+	ref   imageRef
+	token string
+}
+
+func (r *registry) get(path string, accept ...string) (*http.Response, error) {
+	scheme := "https"
+	if strings.HasPrefix(r.ref.registry, "localhost") || strings.HasPrefix(r.ref.registry, "127.0.0.1") {
+		scheme = "http"
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.ref.registry, r.ref.repository, path)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(r.ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+		if r.token != "" {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		}
+		resp, err := registryClient.Do(req)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetching %s", url)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := r.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.Errorf("fetching %s: %s", url, resp.Status)
+		}
+		return resp, nil
+	}
+}
+
+// authenticate gets an anonymous pull token for the repository from the realm of a Bearer challenge
+func (r *registry) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return errors.Errorf("registry %s needs %s authentication, which is not supported", r.ref.registry, scheme)
+	}
+	values := map[string]string{}
+	for _, param := range strings.Split(params, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok {
+			values[key] = strings.Trim(value, `"`)
+		}
+	}
+	req, err := http.NewRequestWithContext(r.ctx, "GET", values["realm"], nil)
+	if err != nil {
+		return errors.Wrapf(err, "authenticating with %s", r.ref.registry)
+	}
+	q := req.URL.Query()
+	q.Set("service", values["service"])
+	q.Set("scope", "repository:"+r.ref.repository+":pull")
+	req.URL.RawQuery = q.Encode()
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "authenticating with %s", r.ref.registry)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("authenticating with %s: %s", r.ref.registry, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return errors.Wrapf(err, "authenticating with %s", r.ref.registry)
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	return nil
+}
+
+func (r *registry) manifest(reference string) (imageManifest, error) {
+	var m imageManifest
+	resp, err := r.get("manifests/"+reference, manifestMediaTypes...)
+	if err != nil {
+		return m, err
+	}
+	defer resp.Body.Close()
+	return m, errors.Wrapf(json.NewDecoder(resp.Body).Decode(&m), "decoding manifest %s", reference)
+}
+
+// imageLayers returns the layers of the image in the registry, for the platform if it's multi-platform
+func (r *registry) imageLayers(platform Platform) ([]string, error) {
+	m, err := r.manifest(r.ref.reference)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Manifests) > 0 {
+		var digest string
+		for _, desc := range m.Manifests {
+			// -goos or -goarch alone give only one of them
+			if (platform.GOOS == "" || desc.Platform.OS == platform.GOOS) && (platform.GOARCH == "" || desc.Platform.Architecture == platform.GOARCH) {
+				digest = desc.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return nil, errors.Errorf("image has no %s variant", platform)
+		}
+		if m, err = r.manifest(digest); err != nil {
+			return nil, err
+		}
+	}
+	var layers []string
+	for _, layer := range m.Layers {
+		if strings.Contains(layer.MediaType, "zstd") {
+			return nil, errors.Errorf("layer %s is zstd compressed, which is not supported", layer.Digest)
+		}
+		layers = append(layers, layer.Digest)
+	}
+	return layers, nil
+}
+
+// imageFiles collects the Go binaries in the layers of an image, applying the whiteouts of each layer to the files
+// of the layers below it. The binaries are written to a temporary directory.
+type imageFiles struct {
+	tmp      string
+	binaries map[string]string // path in the image to the temporary file
+	layer    map[string]bool   // the binaries of the current layer, which its whiteouts don't apply to
+	count    int
+}
+
+// addLayer adds the Go binaries of a layer (a tar file, optionally gzip compressed)
+func (f *imageFiles) addLayer(r io.Reader) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	f.layer = map[string]bool{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean("/" + hdr.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == ".wh..wh..opq":
+			f.remove(dir)
+			continue
+		case strings.HasPrefix(base, ".wh."):
+			f.remove(dir + strings.TrimPrefix(base, ".wh."))
+			continue
+		}
+		delete(f.binaries, name) // replaced by this layer
+		if hdr.Typeflag != tar.TypeReg || hdr.Mode&0111 == 0 {
+			continue
+		}
+		if err := f.addFile(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// addFile adds the file if it's a Go binary
+func (f *imageFiles) addFile(name string, r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	if !isExecutableMagic(magic) {
+		return nil
+	}
+	f.count++
+	tmpFile := filepath.Join(f.tmp, fmt.Sprint(f.count))
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, br)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := buildinfo.ReadFile(tmpFile); err != nil {
+		return os.Remove(tmpFile) // not a Go binary
+	}
+	f.binaries[name] = tmpFile
+	f.layer[name] = true
+	return nil
+}
+
+// remove removes the file, or the files below a directory (ending in /)
+func (f *imageFiles) remove(name string) {
+	for file := range f.binaries {
+		if f.layer[file] {
+			continue
+		}
+		if file == name || strings.HasPrefix(file, strings.TrimSuffix(name, "/")+"/") {
+			delete(f.binaries, file)
+		}
+	}
+}
+
+// isExecutableMagic reports whether the file starts like an ELF, Mach-O or PE executable
+func isExecutableMagic(magic []byte) bool {
+	if len(magic) < 4 {
+		return false
+	}
+	switch {
+	case bytes.Equal(magic, []byte("\x7fELF")), bytes.HasPrefix(magic, []byte("MZ")):
+		return true
+	case bytes.Equal(magic, []byte{0xfe, 0xed, 0xfa, 0xcf}), bytes.Equal(magic, []byte{0xcf, 0xfa, 0xed, 0xfe}):
+		return true
+	}
+	return false
+}
+
+// readImageArchive adds the layers of an image saved with docker save (or an OCI image layout in a tar file)
+func readImageArchive(file string, files *imageFiles) error {
+	var manifest []struct {
+		Layers []string `json:"Layers"`
+	}
+	if err := walkTar(file, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == "manifest.json" {
+			return json.NewDecoder(r).Decode(&manifest)
+		}
+		return nil
+	}); err != nil {
+		return errors.Wrapf(err, "reading %s", file)
+	}
+	if len(manifest) != 1 {
+		return errors.Errorf("%s has %d images; expected an archive of a single image, from docker save", file, len(manifest))
+	}
+	// The layers can be in any order in the archive, so each is read in its own pass
+	for _, layer := range manifest[0].Layers {
+		found := false
+		if err := walkTar(file, func(hdr *tar.Header, r io.Reader) error {
+			if path.Clean(hdr.Name) != path.Clean(layer) {
+				return nil
+			}
+			found = true
+			return files.addLayer(r)
+		}); err != nil {
+			return errors.Wrapf(err, "reading layer %s of %s", layer, file)
+		}
+		if !found {
+			return errors.Errorf("layer %s is missing from %s", layer, file)
+		}
+	}
+	return nil
+}
+
+func walkTar(file string, fn func(*tar.Header, io.Reader) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// pullTimeoutError replaces a deadline exceeded error with one that says that the image pull timed out
+func pullTimeoutError(err error, timeout time.Duration) error {
+	if errors.Cause(err) == context.DeadlineExceeded || errors.Is(err, context.DeadlineExceeded) {
+		return errors.Errorf("the pull did not finish within the timeout of %s (-pull-timeout)", timeout)
+	}
+	return err
+}
+
+// ScanImage checks the Go binaries in a container image, like ScanBinary: the image is an image reference (eg.
+// ghcr.io/foo/bar:tag) that is pulled from its registry, anonymously, or a tar file saved with docker save. For a
+// multi-platform image, the variant of the first of Options.Platforms is used (default linux/amd64). Each binary is
+// reported as a module report, with the image and the path of the binary in it as Dir. With Options.Offline, only tar
+// files can be scanned.
+func ScanImage(image string, opts Options) ([]ModuleReport, error) {
+	o := &opts
+	fi, statErr := os.Stat(image)
+	isArchive := statErr == nil && !fi.IsDir()
+	if !isArchive && o.Offline {
+		return nil, errors.Errorf("can't pull %s from its registry offline; use a tar file saved with docker save", image)
+	}
+	tmp, err := os.MkdirTemp("", "golicenseguard-image-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	files := &imageFiles{tmp: tmp, binaries: map[string]string{}}
+
+	if isArchive {
+		if err := readImageArchive(image, files); err != nil {
+			return nil, err
+		}
+	} else {
+		ctx, cancel := withTimeout(context.Background(), o.PullTimeout)
+		defer cancel()
+		platform := Platform{GOOS: "linux", GOARCH: "amd64"}
+		if len(o.Platforms) > 0 {
+			platform = o.Platforms[0]
+		}
+		r := &registry{ctx: ctx, ref: parseImageRef(image)}
+		layers, err := r.imageLayers(platform)
+		if err != nil {
+			return nil, errors.Wrapf(pullTimeoutError(err, o.PullTimeout), "pulling %s", image)
+		}
+		for _, digest := range layers {
+			resp, err := r.get("blobs/" + digest)
+			if err != nil {
+				return nil, errors.Wrapf(pullTimeoutError(err, o.PullTimeout), "pulling %s", image)
+			}
+			err = files.addLayer(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, errors.Wrapf(pullTimeoutError(err, o.PullTimeout), "reading layer %s of %s", digest, image)
+			}
+		}
+	}
+
+	var reports []ModuleReport
+	for _, name := range sortedKeys(files.binaries) {
+		mr, err := ScanBinary(files.binaries[name], opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "checking %s in %s", name, image)
+		}
+		mr.Dir = image + ":" + name
+		reports = append(reports, *mr)
+	}
+	return reports, nil
+}
@@ -0,0 +1,180 @@
+package licenseguard
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want imageRef
+	}{
+		{"alpine", imageRef{"registry-1.docker.io", "library/alpine", "latest"}},
+		{"alpine:3.20", imageRef{"registry-1.docker.io", "library/alpine", "3.20"}},
+		{"docker.io/alpine", imageRef{"registry-1.docker.io", "library/alpine", "latest"}},
+		{"docker.io/myorg/app:v1", imageRef{"registry-1.docker.io", "myorg/app", "v1"}},
+		{"index.docker.io/library/alpine", imageRef{"registry-1.docker.io", "library/alpine", "latest"}},
+		{"myorg/app:v1", imageRef{"registry-1.docker.io", "myorg/app", "v1"}},
+		{"ghcr.io/myorg/app", imageRef{"ghcr.io", "myorg/app", "latest"}},
+		{"ghcr.io/myorg/app@sha256:0123abcd", imageRef{"ghcr.io", "myorg/app", "sha256:0123abcd"}},
+		{"localhost/app:dev", imageRef{"localhost", "app", "dev"}},
+		{"localhost:5000/app", imageRef{"localhost:5000", "app", "latest"}},
+		{"registry.example.com:5000/team/app:1.0", imageRef{"registry.example.com:5000", "team/app", "1.0"}},
+	}
+	for _, tt := range tests {
+		if got := parseImageRef(tt.ref); got != tt.want {
+			t.Errorf("parseImageRef(%q) = %+v; want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+// TestImageLayersPlatform picks the variant of a multi-platform image by the GOOS and GOARCH that are given
+func TestImageLayersPlatform(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, "/v2/app/manifests/") {
+		case "latest":
+			fmt.Fprint(w, `{"manifests": [
+				{"digest": "sha256:amd64", "platform": {"os": "linux", "architecture": "amd64"}},
+				{"digest": "sha256:arm64", "platform": {"os": "linux", "architecture": "arm64"}}]}`)
+		case "sha256:amd64":
+			fmt.Fprint(w, `{"layers": [{"digest": "sha256:layer-amd64"}]}`)
+		case "sha256:arm64":
+			fmt.Fprint(w, `{"layers": [{"digest": "sha256:layer-arm64"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		platform Platform
+		want     string // layer, or "" for an error
+	}{
+		{Platform{"linux", "amd64"}, "sha256:layer-amd64"},
+		{Platform{"linux", "arm64"}, "sha256:layer-arm64"},
+		{Platform{"", "arm64"}, "sha256:layer-arm64"},
+		{Platform{"linux", ""}, "sha256:layer-amd64"},
+		{Platform{"windows", ""}, ""},
+		{Platform{"linux", "riscv64"}, ""},
+	}
+	for _, tt := range tests {
+		r := &registry{ctx: context.Background(), ref: imageRef{strings.TrimPrefix(srv.URL, "http://"), "app", "latest"}}
+		layers, err := r.imageLayers(tt.platform)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%+v: got layers %v; want an error", tt.platform, layers)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(layers, []string{tt.want}) {
+			t.Errorf("%+v: got %v, %v; want %s", tt.platform, layers, err, tt.want)
+		}
+	}
+}
+
+// TestScanImageOffline doesn't pull an image from its registry with Offline
+func TestScanImageOffline(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	opts := DefaultOptions()
+	opts.Offline = true
+	if _, err := ScanImage(strings.TrimPrefix(srv.URL, "http://")+"/app", opts); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("got error %v; want one about being offline", err)
+	}
+	if requests > 0 {
+		t.Errorf("got %d requests to the registry; want none", requests)
+	}
+}
+
+// tarEntry is a regular file of a test layer
+type tarEntry struct {
+	name string
+	mode int64
+	body []byte
+}
+
+// testLayer returns a layer tar file with the entries, gzip compressed if compress is set
+func testLayer(t *testing.T, compress bool, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var b bytes.Buffer
+	var gz *gzip.Writer
+	tw := tar.NewWriter(&b)
+	if compress {
+		gz = gzip.NewWriter(&b)
+		tw = tar.NewWriter(gz)
+	}
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: e.mode, Size: int64(len(e.body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &b
+}
+
+// TestImageLayerWhiteouts checks which Go binaries are left after applying the whiteouts of the upper layers
+func TestImageLayerWhiteouts(t *testing.T) {
+	exe, err := os.Executable() // the test binary is a Go binary
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := []*bytes.Buffer{
+		testLayer(t, false,
+			tarEntry{"app/a", 0755, binary},
+			tarEntry{"app/b", 0755, binary},
+			tarEntry{"bin/c", 0755, binary},
+			tarEntry{"opt/tools/d", 0755, binary},
+			tarEntry{"etc/script", 0755, []byte("#!/bin/sh\n")},
+			tarEntry{"usr/bin/notexec", 0644, binary},
+		),
+		testLayer(t, true,
+			tarEntry{"app/.wh.a", 0644, nil},
+			tarEntry{"opt/e", 0755, binary},
+			tarEntry{"opt/.wh..wh..opq", 0644, nil}, // doesn't remove opt/e, which is in the same layer
+		),
+		testLayer(t, false,
+			tarEntry{"bin/c", 0644, []byte("replaced by a text file")},
+		),
+	}
+	files := &imageFiles{tmp: t.TempDir(), binaries: map[string]string{}}
+	for _, layer := range layers {
+		if err := files.addLayer(layer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for name := range files.binaries {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	if want := []string{"/app/b", "/opt/e"}; !slices.Equal(got, want) {
+		t.Errorf("got binaries %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,187 @@
+package licenseguard
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// scanStateVersion is bumped when the license detection changes, so the licenses in older state files are found again
+const scanStateVersion = 1
+
+// scanState is the Options.Incremental file: the licenses that were detected for the packages of each module in the
+// module cache, with the module's go.sum hash. A module with the same hash in the next scan has the same files, so
+// the licenses of its packages are reused instead of looking for them again.
+type scanState struct {
+	Version int                         `json:"version"`
+	Options string                      `json:"options"` // the detection options, see detectionOptions
+	Modules map[string]*scanStateModule `json:"modules"` // module@version -> packages
+}
+
+type scanStateModule struct {
+	Sum      string                      `json:"sum"`      // h1: hash of the module in go.sum
+	Packages map[string]scanStatePackage `json:"packages"` // package directory -> detected license
+}
+
+// scanStatePackage is what detectLicense found for a package
+type scanStatePackage struct {
+	Files        []string          `json:"files"` // source files, which depend on the platform and build tags
+	License      string            `json:"license"`
+	Source       LicenseSource     `json:"source"`
+	File         string            `json:"file,omitempty"`
+	Distance     int               `json:"distance,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Confidence   map[string]int    `json:"confidence,omitempty"`
+	FileLicenses map[string]string `json:"fileLicenses,omitempty"`
+	Conflict     string            `json:"conflict,omitempty"`
+}
+
+// detectionOptions returns the options that change the outcome of detectLicense
+func (o *Options) detectionOptions() string {
+	return fmt.Sprintf("minConfidence=%d conflictPolicy=%s scanReadme=%t corpus=%s", o.MinConfidence, o.ConflictPolicy, o.ScanReadme, corpusId(o.corpus))
+}
+
+// loadScanState reads the state file of the previous scan; a missing, outdated or corrupt file is an empty state
+func loadScanState(file string, o *Options) (*scanState, error) {
+	empty := &scanState{Version: scanStateVersion, Options: o.detectionOptions(), Modules: map[string]*scanStateModule{}}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return empty, nil
+		}
+		return nil, errors.Wrap(err, "reading the incremental scan state")
+	}
+	var state scanState
+	if err := json.Unmarshal(data, &state); err != nil || state.Version != empty.Version || state.Options != empty.Options || state.Modules == nil {
+		return empty, nil // it will be overwritten
+	}
+	return &state, nil
+}
+
+// save writes the state to file
+func (s *scanState) save(file string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(file, data, 0644), "writing the incremental scan state")
+}
+
+// moduleKey returns the module@version of a package in the module cache, or "" if its files may change
+func (p *Package) moduleKey() string {
+	if !p.inModuleCache() {
+		return ""
+	}
+	return p.Module.Path + "@" + p.Module.Version
+}
+
+// lookup returns the license that was detected for the package in the previous scan, if its module has the same hash
+func (s *scanState) lookup(p *Package, sums map[string]string) (scanStatePackage, bool) {
+	key := p.moduleKey()
+	if s == nil || key == "" {
+		return scanStatePackage{}, false
+	}
+	m := s.Modules[key]
+	if m == nil || m.Sum == "" || m.Sum != sums[key] {
+		return scanStatePackage{}, false
+	}
+	entry, ok := m.Packages[p.Dir]
+	return entry, ok && slices.Equal(entry.Files, p.sourceFiles())
+}
+
+// detectLicenseIncremental is detectLicense, reusing the license from the Options.Incremental state if the package's
+// module is unchanged
+func (p *Package) detectLicenseIncremental(o *Options) (string, error) {
+	entry, ok := o.previousScan.lookup(p, o.goSums)
+	if !ok {
+		return p.detectLicense(o)
+	}
+	p.licenseSource, p.licenseFile, p.licenseDistance, p.licenseURL = entry.Source, entry.File, entry.Distance, entry.URL
+	p.licenseConfidence, p.fileLicenses, p.licenseConflict = entry.Confidence, entry.FileLicenses, entry.Conflict
+	p.licenseCached = append(p.licenseCached, "unchanged since the previous scan in "+o.Incremental)
+	if o.Log != nil {
+		o.Log.Info("license", "package", p.ImportPath, "license", entry.License, "source", "unchanged since the previous scan")
+	}
+	return entry.License, nil
+}
+
+// nextScanState returns the state with the detected licenses of the packages of this scan, and logs how many modules
+// were unchanged. Only the modules of this scan are kept, and only the licenses that were detected (not overrides,
+// reviews or index licenses, which don't depend on the module's files).
+func nextScanState(byImportPath map[ImportPath]*Package, o *Options) *scanState {
+	next := &scanState{Version: scanStateVersion, Options: o.detectionOptions(), Modules: map[string]*scanStateModule{}}
+	unchanged := map[string]bool{}
+	for _, p := range byImportPath {
+		key := p.moduleKey()
+		sum := o.goSums[key]
+		if key == "" || sum == "" {
+			continue
+		}
+		m := next.Modules[key]
+		if m == nil {
+			m = &scanStateModule{Sum: sum, Packages: map[string]scanStatePackage{}}
+			next.Modules[key] = m
+		}
+		if prev := o.previousScan.Modules[key]; prev != nil && prev.Sum == sum {
+			unchanged[key] = true
+		}
+		if p.license == "" {
+			continue
+		}
+		switch p.licenseSource {
+		case LicenseSourceHeader, LicenseSourceFile, LicenseSourceReuse, LicenseSourceEmbed, LicenseSourceReadme:
+		default:
+			continue
+		}
+		lic := p.license
+		if p.licenseExpression != "" {
+			lic = p.licenseExpression // before chooseLicense
+		}
+		m.Packages[p.Dir] = scanStatePackage{Files: p.sourceFiles(), License: lic, Source: p.licenseSource, File: p.licenseFile,
+			Distance: p.licenseDistance, URL: p.licenseURL, Confidence: p.licenseConfidence, FileLicenses: p.fileLicenses,
+			Conflict: p.licenseConflict}
+	}
+	if o.Log != nil {
+		o.Log.Info("incremental scan", "unchanged", len(unchanged), "modules", len(next.Modules))
+	}
+	return next
+}
+
+// readGoSums returns the h1: hash of each module@version in the go.sum files of the main modules and go.work.sum in
+// dir, if any
+func readGoSums(dir string, byImportPath map[ImportPath]*Package) (map[string]string, error) {
+	files := []string{filepath.Join(dir, "go.work.sum")}
+	for _, p := range byImportPath {
+		if p.Module != nil && p.Module.Main && p.Module.Dir != "" {
+			files = appendUnique(files, filepath.Join(p.Module.Dir, "go.sum"))
+		}
+	}
+	sums := map[string]string{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) == 3 && !strings.HasSuffix(fields[1], "/go.mod") {
+				sums[fields[0]+"@"+fields[1]] = fields[2]
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", file)
+		}
+	}
+	return sums, nil
+}
@@ -0,0 +1,68 @@
+package licenseguard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIncrementalScan reuses the license of a package from the state of the previous scan while its module's go.sum
+// hash, its files and the detection options are the same
+func TestIncrementalScan(t *testing.T) {
+	bsd, err := os.ReadFile(filepath.Join("testdata", "vendored", "vendor", "example.com", "bsd", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modcache := t.TempDir()
+	t.Setenv("GOMODCACHE", modcache)
+	root := filepath.Join(modcache, "example.com", "inc@v1.0.0")
+	writeFiles(t, root, map[string]string{"LICENSE": mitLicense, "a/a.go": "package a\n", "a/b.go": "package a\n"})
+	state := filepath.Join(t.TempDir(), "state.json")
+	newPackage := func(goFiles ...string) *Package {
+		return &Package{Dir: filepath.Join(root, "a"), ImportPath: "example.com/inc/a", GoFiles: goFiles,
+			Module: &Module{Path: "example.com/inc", Version: "v1.0.0", Dir: root}}
+	}
+	scan := func(sum string, minConfidence int, p *Package) string {
+		t.Helper()
+		opts := DefaultOptions()
+		opts.Incremental, opts.MinConfidence = state, minConfidence
+		opts.goSums = map[string]string{"example.com/inc@v1.0.0": sum}
+		if opts.previousScan, err = loadScanState(state, &opts); err != nil {
+			t.Fatal(err)
+		}
+		if p.license, err = p.detectLicenseIncremental(&opts); err != nil {
+			t.Fatal(err)
+		}
+		if err := nextScanState(map[ImportPath]*Package{"example.com/inc/a": p}, &opts).save(state); err != nil {
+			t.Fatal(err)
+		}
+		return p.license
+	}
+
+	tests := []struct {
+		name          string
+		sum           string
+		minConfidence int
+		goFiles       []string
+		want          string
+	}{
+		// the module can't change without changing its hash, so the license file isn't read again
+		{"unchanged", "h1:first", 50, []string{"a.go", "b.go"}, "MIT"},
+		{"other files", "h1:first", 50, []string{"a.go"}, "BSD-3-Clause"},
+		{"other options", "h1:first", 60, []string{"a.go", "b.go"}, "BSD-3-Clause"},
+		{"other hash", "h1:second", 50, []string{"a.go", "b.go"}, "BSD-3-Clause"},
+	}
+	for _, tt := range tests {
+		os.Remove(state)
+		writeFiles(t, root, map[string]string{"LICENSE": mitLicense})
+		resetLicenseDirCache()
+		if lic := scan("h1:first", 50, newPackage("a.go", "b.go")); lic != "MIT" {
+			t.Fatalf("%s: got %q in the first scan; want MIT", tt.name, lic)
+		}
+		writeFiles(t, root, map[string]string{"LICENSE": string(bsd)})
+		resetLicenseDirCache()
+		if lic := scan(tt.sum, tt.minConfidence, newPackage(tt.goFiles...)); lic != tt.want {
+			t.Errorf("%s: got %q; want %s", tt.name, lic, tt.want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package licenseguard
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// licenseCacheKey is the key of licenseIdCache; the license depends on the minimum confidence and the custom licenses
+type licenseCacheKey struct {
+	file          string
+	minConfidence int
+	corpus        string // see corpusId
+}
+
+// licenseCacheEntry is a cached license, valid while the size and modification time of the file are unchanged
+type licenseCacheEntry struct {
+	File          string       `json:"file"`
+	MinConfidence int          `json:"minConfidence"`
+	Corpus        string       `json:"corpus,omitempty"`
+	Size          int64        `json:"size"`
+	ModTime       int64        `json:"modTime"` // in nanoseconds since the epoch
+	Match         licenseMatch `json:"match"`
+}
+
+var (
+	licenseIdCache      = map[licenseCacheKey]licenseCacheEntry{} // file -> license mapping
+	licenseIdCacheMu    sync.Mutex
+	licenseIdCacheDirty bool
+	licenseScans        = map[licenseCacheKey]chan struct{}{} // license files being scanned; closed when done
+)
+
+// headerCacheEntry is the license of the source headers of a package in the module cache, which is immutable, so
+// the entry is valid as long as the package has the same files (which depend on the platform and build tags)
+type headerCacheEntry struct {
+	Dir          string            `json:"dir"` // in the module cache, so it includes the module version
+	Files        []string          `json:"files"`
+	License      string            `json:"license,omitempty"`
+	FileLicenses map[string]string `json:"fileLicenses,omitempty"` // if the files have different licenses
+	Corpus       string            `json:"corpus,omitempty"`       // see corpusId
+	Conflict     string            `json:"conflict,omitempty"`     // written by older versions, without the files
+	None         bool              `json:"none,omitempty"`         // not all files have a license header
+}
+
+var (
+	headerCache      = map[string]headerCacheEntry{} // dir -> license headers
+	headerCacheMu    sync.Mutex
+	headerCacheDirty bool
+)
+
+var (
+	licenseDirCache   = map[string]string{} // directory -> license file in it, or "" if none
+	licenseDirCacheMu sync.Mutex
+
+	// moduleLicenseCache has the license file of the root of the modules in the module cache, which never change, so
+	// unlike the licenseDirCache it's kept between scans
+	moduleLicenseCache   = map[string]string{} // module path@version -> license file in its root, or "" if none
+	moduleLicenseCacheMu sync.Mutex
+)
+
+// resetLicenseDirCache forgets the license files of the directories, which may have changed since the last Scan
+func resetLicenseDirCache() {
+	licenseDirCacheMu.Lock()
+	clear(licenseDirCache)
+	licenseDirCacheMu.Unlock()
+}
+
+// cacheStats counts the lookups that were answered from the caches and the work that was done for the others
+var cacheStats struct {
+	dirHits, dirReads        atomic.Int64 // license file lookups in a directory
+	fileHits, fileScans      atomic.Int64 // license detection in a license file
+	headerHits               atomic.Int64 // license headers of a package in the module cache
+	moduleHits, moduleMisses atomic.Int64 // license file lookups in the root of a module in the module cache
+}
+
+// cacheCounts returns the current cacheStats counters
+func cacheCounts() [7]int64 {
+	return [7]int64{cacheStats.dirHits.Load(), cacheStats.dirReads.Load(), cacheStats.fileHits.Load(), cacheStats.fileScans.Load(),
+		cacheStats.headerHits.Load(), cacheStats.moduleHits.Load(), cacheStats.moduleMisses.Load()}
+}
+
+// inModuleCache reports whether the package's files are in the module cache, and therefore never change
+func (p *Package) inModuleCache() bool {
+	if p.Module == nil || p.Module.Version == "" || p.Module.Replace != nil || p.Dir == "" {
+		return false
+	}
+	return strings.HasPrefix(p.Dir, moduleCacheRoot()+string(filepath.Separator))
+}
+
+// findLicenseHeadersCached is findLicenseHeaders, caching the result for packages in the module cache
+func (p *Package) findLicenseHeadersCached(lc *licenseCorpus) (string, map[string]string, error) {
+	if !p.inModuleCache() {
+		return findLicenseHeaders(p.Dir, p.sourceFiles(), lc)
+	}
+	headerCacheMu.Lock()
+	entry, ok := headerCache[p.Dir]
+	headerCacheMu.Unlock()
+	if ok && slices.Equal(entry.Files, p.sourceFiles()) && entry.Corpus == corpusId(lc) {
+		cacheStats.headerHits.Add(1)
+		p.licenseCached = append(p.licenseCached, "source headers from the header cache")
+		if entry.None {
+			return "", nil, ErrNoLicense
+		}
+		return entry.License, entry.FileLicenses, nil
+	}
+	lic, fileLicenses, err := findLicenseHeaders(p.Dir, p.sourceFiles(), lc)
+	switch errors.Cause(err) {
+	case nil, ErrNoLicense, ErrUnknownLicense, ErrEmptyLicense:
+		entry = headerCacheEntry{Dir: p.Dir, Files: p.sourceFiles(), License: lic, FileLicenses: fileLicenses, Corpus: corpusId(lc), None: err != nil}
+	default:
+		return lic, fileLicenses, err // not cached
+	}
+	headerCacheMu.Lock()
+	headerCache[p.Dir] = entry
+	headerCacheDirty = true
+	headerCacheMu.Unlock()
+	return lic, fileLicenses, err
+}
+
+// findLicenseFileCached is findLicenseFile, caching the result for each directory: the packages of a module all end
+// up looking in the module root, and packages in the same directory (like test packages) in the same directories
+func findLicenseFileCached(dir string) (string, error) {
+	licenseDirCacheMu.Lock()
+	file, ok := licenseDirCache[dir]
+	licenseDirCacheMu.Unlock()
+	if ok {
+		cacheStats.dirHits.Add(1)
+		if file == "" {
+			return "", ErrNoLicense
+		}
+		return file, nil
+	}
+	cacheStats.dirReads.Add(1)
+	file, err := findLicenseFile(dir)
+	if err != nil && err != ErrNoLicense {
+		return "", err // not cached
+	}
+	licenseDirCacheMu.Lock()
+	licenseDirCache[dir] = file
+	licenseDirCacheMu.Unlock()
+	return file, err
+}
+
+// findModuleLicenseFileCached is findLicenseFileCached for the root directory of the module (path@version) in the
+// module cache, caching the result for the module
+func findModuleLicenseFileCached(module, dir string) (string, error) {
+	moduleLicenseCacheMu.Lock()
+	file, ok := moduleLicenseCache[module]
+	moduleLicenseCacheMu.Unlock()
+	if ok {
+		cacheStats.moduleHits.Add(1)
+		if file == "" {
+			return "", ErrNoLicense
+		}
+		return file, nil
+	}
+	cacheStats.moduleMisses.Add(1)
+	file, err := findLicenseFileCached(dir)
+	if err != nil && err != ErrNoLicense {
+		return "", err // not cached
+	}
+	moduleLicenseCacheMu.Lock()
+	moduleLicenseCache[module] = file
+	moduleLicenseCacheMu.Unlock()
+	return file, err
+}
+
+// licenseCacheFile returns the path of a cache file in the user cache directory, or "" if there is none
+func licenseCacheFile(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "golicenseguard", name)
+}
+
+// LoadLicenseCache loads the licenses of the license files that were scanned before, and the license headers of the
+// packages in the module cache, if any
+func LoadLicenseCache() error {
+	if err := loadHeaderCache(); err != nil {
+		return err
+	}
+	file := licenseCacheFile("licenses.json")
+	if file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var entries []licenseCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil // ignore a corrupt cache; it will be overwritten
+	}
+	licenseIdCacheMu.Lock()
+	defer licenseIdCacheMu.Unlock()
+	for _, entry := range entries {
+		if entry.Match.Confidence == nil {
+			continue // written by an older version, which only had the confidence with a minimum confidence
+		}
+		licenseIdCache[licenseCacheKey{entry.File, entry.MinConfidence, entry.Corpus}] = entry
+	}
+	return nil
+}
+
+// SaveLicenseCache saves the licenses of the scanned license files and of the license headers, if any new ones were
+// scanned
+func SaveLicenseCache() error {
+	if err := saveHeaderCache(); err != nil {
+		return err
+	}
+	file := licenseCacheFile("licenses.json")
+	licenseIdCacheMu.Lock()
+	defer licenseIdCacheMu.Unlock()
+	if file == "" || !licenseIdCacheDirty {
+		return nil
+	}
+	entries := make([]licenseCacheEntry, 0, len(licenseIdCache))
+	for _, entry := range licenseIdCache {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	licenseIdCacheDirty = false
+	return nil
+}
+
+func readLicenseFileCached(licenseFile string, minConfidence int, lc *licenseCorpus) (licenseMatch, error) {
+	fi, err := os.Stat(licenseFile)
+	if err != nil {
+		return readLicense(licenseFile, minConfidence, lc)
+	}
+	key := licenseCacheKey{licenseFile, minConfidence, corpusId(lc)}
+	licenseIdCacheMu.Lock()
+	entry, ok := licenseIdCache[key]
+	if ok && entry.Size == fi.Size() && entry.ModTime == fi.ModTime().UnixNano() {
+		licenseIdCacheMu.Unlock()
+		cacheStats.fileHits.Add(1)
+		entry.Match.cached = true
+		return entry.Match, nil
+	}
+	if scan, ok := licenseScans[key]; ok {
+		// Another package's worker is scanning the same file (eg. the module root's LICENSE); wait for its result
+		licenseIdCacheMu.Unlock()
+		<-scan
+		return readLicenseFileCached(licenseFile, minConfidence, lc)
+	}
+	scan := make(chan struct{})
+	licenseScans[key] = scan
+	licenseIdCacheMu.Unlock()
+	cacheStats.fileScans.Add(1)
+
+	match, err := readLicense(licenseFile, minConfidence, lc)
+	licenseIdCacheMu.Lock()
+	defer licenseIdCacheMu.Unlock()
+	delete(licenseScans, key)
+	close(scan)
+	if err != nil {
+		return licenseMatch{}, err
+	}
+	licenseIdCache[key] = licenseCacheEntry{File: licenseFile, MinConfidence: minConfidence, Corpus: key.corpus, Size: fi.Size(), ModTime: fi.ModTime().UnixNano(), Match: match}
+	licenseIdCacheDirty = true
+	return match, nil
+}
+
+func loadHeaderCache() error {
+	file := licenseCacheFile("headers.json")
+	if file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var entries []headerCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil // ignore a corrupt cache; it will be overwritten
+	}
+	headerCacheMu.Lock()
+	defer headerCacheMu.Unlock()
+	for _, entry := range entries {
+		if entry.Conflict != "" {
+			continue // written by an older version, which didn't have the license of each file
+		}
+		headerCache[entry.Dir] = entry
+	}
+	return nil
+}
+
+func saveHeaderCache() error {
+	file := licenseCacheFile("headers.json")
+	headerCacheMu.Lock()
+	defer headerCacheMu.Unlock()
+	if file == "" || !headerCacheDirty {
+		return nil
+	}
+	entries := make([]headerCacheEntry, 0, len(headerCache))
+	for _, entry := range headerCache {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	headerCacheDirty = false
+	return nil
+}
@@ -0,0 +1,1332 @@
+package licenseguard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/licensecheck"
+	"github.com/pkg/errors"
+)
+
+var ErrNoLicense = fmt.Errorf("no license found")
+
+// ErrEmptyLicense is returned when a license file is empty or contains only whitespace, which is likely a packaging bug.
+var ErrEmptyLicense = fmt.Errorf("empty license file")
+
+// UnknownLicense is the license of packages whose license could not be determined
+const UnknownLicense = "Unknown"
+
+// ErrUnknownLicense is returned when licensecheck recognizes license text but cannot identify it.
+var ErrUnknownLicense = fmt.Errorf("unidentified license")
+
+// ErrLowConfidence is returned when the license matches in a license file are all below Options.MinConfidence.
+var ErrLowConfidence = fmt.Errorf("low confidence license match")
+
+// isScanError reports whether the license could not be determined because a file or directory of the package could
+// not be read, rather than because it has no (recognizable) license
+func isScanError(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr)
+}
+
+// licenseDirs are the subdirectories that are checked for a license file when there's none in the directory itself
+var licenseDirs = []string{"legal", "docs"}
+
+// findLicenseFile returns the license file in dir, or else the only license in its REUSE LICENSES directory, or else
+// the license file in one of its licenseDirs
+func findLicenseFile(dir string) (string, error) {
+	file, err := findFile(dir, isLicenseFile)
+	if err != ErrNoLicense {
+		return file, err
+	}
+	if file, err := findReuseLicensesDir(filepath.Join(dir, "LICENSES")); err != ErrNoLicense {
+		return file, err
+	}
+	for _, sub := range licenseDirs {
+		if sub := filepath.Join(dir, sub); isDir(sub) {
+			if file, err := findFile(sub, isLicenseFile); err != ErrNoLicense {
+				return file, err
+			}
+		}
+	}
+	return "", ErrNoLicense
+}
+
+// findReuseLicensesDir returns the license file in a REUSE LICENSES directory, where each license has a file named after
+// its SPDX ID, like LICENSES/MIT.txt. With more than one license, which files they apply to is declared in the
+// source headers or .reuse/dep5, so no single file is returned.
+func findReuseLicensesDir(dir string) (string, error) {
+	if !isDir(dir) {
+		return "", ErrNoLicense
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(files) != 1 {
+		return "", ErrNoLicense
+	}
+	return files[0], nil
+}
+
+func isDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
+// findFile returns the first file in dir for which match(lowercase name) is true
+func findFile(dir string, match func(lower string) bool) (string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return "", err
+	}
+	entries, err := f.ReadDir(-1)
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if match(strings.ToLower(entry.Name())) {
+			return filepath.Join(dir, entry.Name()), nil
+		}
+	}
+	return "", ErrNoLicense
+}
+
+func isLicenseFile(lower string) bool {
+	switch lower {
+	case "copying", "copying.md", "copying.markdown", "copying.txt", // this list is from https://pkg.go.dev/license-policy
+		"licence", "licence.md", "licence.markdown", "licence.txt",
+		"license", "license.md", "license.markdown", "license.txt",
+		"license-2.0.txt", "licence-2.0.txt", "license-apache", "licence-apache",
+		"license-apache-2.0.txt", "licence-apache-2.0.txt", "license-mit", "licence-mit",
+		"license.mit", "licence.mit", "license.code", "licence.code",
+		"license.docs", "licence.docs", "license.rst", "licence.rst",
+		"mit-license", "mit-licence", "mit-license.md", "mit-licence.md",
+		"mit-license.markdown", "mit-licence.markdown", "mit-license.txt", "mit-licence.txt",
+		"mit_license", "mit_licence", "unlicense", "unlicence",
+		"license_apache2": // used by grafana/loki
+		return true
+	}
+	return false
+}
+
+// FindLicenseFileUp looks for a license file in dir or its parents, up to the module root moduleDir, and returns the
+// number of directories it climbed. Without a moduleDir, it stops at the module@version directory in the module cache.
+func FindLicenseFileUp(dir, moduleDir string) (string, int, error) {
+	return walkUp(dir, moduleDir, findLicenseFileCached)
+}
+
+// findFileUp looks for a file for which match(lowercase name) is true in dir or its parents, like FindLicenseFileUp
+func findFileUp(dir, moduleDir string, match func(lower string) bool) (string, int, error) {
+	return walkUp(dir, moduleDir, func(dir string) (string, error) { return findFile(dir, match) })
+}
+
+// walkUp calls find for dir and its parents, up to the module root, until it finds a file
+func walkUp(dir, moduleDir string, find func(dir string) (string, error)) (string, int, error) {
+	for distance := 0; ; distance++ {
+		file, err := find(dir)
+		if err != nil {
+			if err != ErrNoLicense {
+				return "", 0, err
+			}
+		} else {
+			return file, distance, nil
+		}
+		if moduleDir != "" && filepath.Clean(dir) == filepath.Clean(moduleDir) {
+			break // checked the module root
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		if moduleDir == "" && !strings.Contains(dir, "@") {
+			break
+		}
+		if moduleDir != "" && !isWithin(dir, moduleDir) {
+			break // dir wasn't in the module
+		}
+	}
+	return "", 0, ErrNoLicense
+}
+
+// isWithin reports whether dir is root or a subdirectory of it
+func isWithin(dir, root string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// splitException splits a license expression like "GPL-2.0-only WITH Classpath-exception-2.0" into the license and the exception
+func splitException(lic string) (string, string) {
+	if fields := strings.Fields(lic); len(fields) == 3 && strings.EqualFold(fields[1], "WITH") {
+		return fields[0], fields[2]
+	}
+	return strings.TrimSpace(lic), ""
+}
+
+// isAcceptedException reports whether the exception is one of the AcceptExceptions, or of the acceptExceptions of the
+// configuration file, ignoring case; blank entries accept nothing
+func (o *Options) isAcceptedException(exception string) bool {
+	if exception == "" {
+		return false
+	}
+	for _, accepted := range slices.Concat(o.AcceptExceptions, o.Config.AcceptExceptions) {
+		if accepted = strings.TrimSpace(accepted); accepted != "" && strings.EqualFold(exception, accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDenied reports whether the license (with the given URL, if any) is in the Deny list, and can therefore not be used
+// by code with a license that's not denied.
+// Note that licensecheck itself doesn't report exceptions, so "WITH" expressions only come from other sources.
+func (o *Options) isDenied(lic, url string) bool {
+	if matchURL(o.Config.AllowURLs, url) {
+		return false
+	}
+	if matchURL(o.Config.DenyURLs, url) {
+		return true
+	}
+	if lic == "" || lic == UnknownLicense {
+		return false // see FailUnknown
+	}
+	// Denied unless some choice of licenses in the expression avoids all denied ones
+	return !licenseSatisfied(lic, func(lic string) bool { return !o.isDeniedLicense(lic) })
+}
+
+// isDeniedLicense reports whether a single license (with an optional exception) matches the Deny list. A license
+// with an accepted exception, or with an exception that's in the Allow list (eg. GPL-2.0-only WITH
+// Classpath-exception-2.0), is not denied.
+func (o *Options) isDeniedLicense(lic string) bool {
+	if _, exception := splitException(lic); exception != "" {
+		if o.isAcceptedException(exception) {
+			return false
+		}
+		for _, allowed := range o.Config.Allow {
+			if _, allowedException := splitException(allowed); allowedException != "" && matchLicenseTerm(lic, allowed, false) {
+				return false
+			}
+		}
+	}
+	deny := o.Deny
+	if len(deny) == 0 {
+		deny = o.Config.Deny
+	}
+	if len(deny) == 0 {
+		deny = DefaultDeny
+	}
+	for _, denied := range deny {
+		if matchLicenseTerm(lic, denied, true) {
+			return true
+		}
+	}
+	return false
+}
+
+type ImportPath string
+
+func normalizeImportPath(importPath string) ImportPath {
+	return ImportPath(strings.TrimPrefix(importPath, "vendor/"))
+}
+
+// licenseMatch is the license detected in a license file
+type licenseMatch struct {
+	ID  string   `json:"id"`            // license ID, or an "A OR B" expression if the file has more than one license
+	IDs []string `json:"ids"`           // distinct license IDs, sorted
+	URL string   `json:"url,omitempty"` // if the license was identified by its URL
+	// Confidence is the highest matchConfidence of each ID
+	Confidence map[string]int `json:"confidence,omitempty"`
+
+	cached bool // from licenseIdCache
+}
+
+// FindLicense returns the license of the package, detecting it the first time; if the license could not be
+// determined, it returns UnknownLicense and the error
+func (p *Package) FindLicense(o *Options) (string, error) {
+	if p.license != "" {
+		return p.license, nil
+	}
+	if p.Standard {
+		return "standard", nil
+	}
+	if p.ForTest != "" && !o.IncludeTests {
+		return "test", nil
+	}
+	// The code is the replacement's, so an override of the replacement (eg. a fork) takes precedence
+	if importPath, version, ok := p.replacementImportPath(); ok {
+		if lic := o.findOverride(importPath, version); lic != "" {
+			p.license, p.licenseSource = lic, LicenseSourceOverride
+			return lic, nil
+		}
+	}
+	if lic := o.findOverride(normalizeImportPath(p.ImportPath), p.moduleVersion()); lic != "" {
+		p.license, p.licenseSource = lic, LicenseSourceOverride
+		return lic, nil
+	}
+
+	licenseId, err := p.detectLicenseIncremental(o)
+	if err != nil {
+		// Fall back to a human-reviewed license, if any
+		if review := o.Config.findReview(normalizeImportPath(p.ImportPath)); review != nil {
+			p.license, p.licenseSource = review.License, LicenseSourceReview
+			return review.License, nil
+		}
+		// or else a license file above the module in its repository, like the root of a monorepo
+		if cause := errors.Cause(err); o.vcsFallback() && (cause == ErrNoLicense || cause == ErrNotInModuleCache) {
+			file, match, vcsErr := p.vcsLicense(o)
+			if vcsErr == nil {
+				p.license, p.licenseSource, p.licenseFile, p.licenseURL, p.licenseConfidence = match.ID, LicenseSourceVCS, file, match.URL, match.Confidence
+				return match.ID, nil
+			}
+			if errors.Cause(vcsErr) != ErrNoLicense && !o.indexFallback() {
+				return UnknownLicense, errors.Wrapf(err, "VCS fallback failed (%v)", vcsErr)
+			}
+		}
+		// or else the license the package index detected, which handles unusual license locations
+		if cause := errors.Cause(err); o.indexFallback() && (cause == ErrNoLicense || cause == ErrNotInModuleCache) {
+			lic, indexErr := p.indexLicense()
+			if indexErr != nil {
+				return UnknownLicense, errors.Wrapf(err, "deps.dev fallback failed (%v)", indexErr)
+			}
+			if lic != "" {
+				p.license, p.licenseSource = lic, LicenseSourceIndex
+				return lic, nil
+			}
+		}
+		return UnknownLicense, err
+	}
+	p.license = licenseId
+	prefer := o.Prefer
+	if len(prefer) == 0 {
+		prefer = o.Config.Prefer
+	}
+	if chosen := chooseLicense(licenseId, prefer); chosen != licenseId {
+		p.license, p.licenseExpression = chosen, licenseId
+	}
+	return p.license, nil
+}
+
+// moduleDir returns the root directory of the package's module, or "" if unknown
+func (p *Package) moduleDir() string {
+	if p.Module == nil {
+		return ""
+	}
+	if p.Module.Dir == "" {
+		return p.vendorModuleDir()
+	}
+	return p.Module.Dir // for replaced modules, this is the directory of the replacement
+}
+
+// replacement returns the replacement of the package's module, as path@version, or the directory of a local
+// replacement as written in go.mod; "" if the module isn't replaced
+func (p *Package) replacement() string {
+	if p.Module == nil || p.Module.Replace == nil {
+		return ""
+	}
+	if p.Module.Replace.Version == "" {
+		return p.Module.Replace.Path
+	}
+	return p.Module.Replace.Path + "@" + p.Module.Replace.Version
+}
+
+// replacementImportPath returns the import path of the package in the module that replaces its module, and the
+// version of that module, or false if the module isn't replaced by another module (eg. by a fork)
+func (p *Package) replacementImportPath() (ImportPath, string, bool) {
+	if p.Module == nil || p.Module.Replace == nil || p.Module.Replace.Version == "" {
+		return "", "", false
+	}
+	rel, ok := strings.CutPrefix(p.ImportPath, p.Module.Path)
+	if !ok {
+		return "", "", false
+	}
+	return ImportPath(p.Module.Replace.Path + rel), p.Module.Replace.Version, true
+}
+
+// moduleVersion returns the required version of the package's module, or "" for the main module and GOPATH
+func (p *Package) moduleVersion() string {
+	if p.Module == nil {
+		return ""
+	}
+	return p.Module.Version
+}
+
+// vendorModuleDir returns the root directory of the vendored copy of the package's module (-mod=vendor), for which
+// go list (which reads vendor/modules.txt) reports the module but not its directory; or "" if the package isn't vendored
+func (p *Package) vendorModuleDir() string {
+	rel, ok := strings.CutPrefix(p.ImportPath, p.Module.Path)
+	if !ok {
+		return ""
+	}
+	dir, ok := strings.CutSuffix(filepath.ToSlash(p.Dir), rel)
+	if !ok || !strings.Contains(dir+"/", "/vendor/") {
+		return ""
+	}
+	return filepath.FromSlash(dir)
+}
+
+// detectLicense detects the license from the package's files
+func (p *Package) detectLicense(o *Options) (string, error) {
+	if p.Dir == "" {
+		// eg. a module that could not be downloaded; the files of the current directory are not the package's
+		return "", errors.Wrapf(ErrNoLicense, "package %s has no directory", p.ImportPath)
+	}
+	// REUSE metadata, if present, is authoritative
+	if moduleDir := p.moduleDir(); moduleDir != "" {
+		if licenseId, err := findReuseLicense(moduleDir, p.Dir, p.sourceFiles()); err == nil {
+			p.licenseSource = LicenseSourceReuse
+			return licenseId, nil
+		}
+	}
+
+	// Check whether (all) the source files contain a license header
+	headerId, fileLicenses, err := p.findLicenseHeadersCached(o.corpus)
+	if isScanError(err) {
+		return "", errors.Wrapf(err, "reading the license headers of %s", p.ImportPath)
+	}
+	p.fileLicenses, p.licenseConflict = fileLicenses, mixedLicenses(fileLicenses)
+	if err != nil {
+		// Check whether the package embeds its license text with //go:embed
+		if embedded, err := findEmbeddedLicense(p.Dir, p.sourceFiles()); err == nil {
+			if match, err := readLicenseFileCached(embedded, o.MinConfidence, o.corpus); err == nil {
+				p.licenseSource, p.licenseFile, p.licenseURL, p.licenseConfidence = LicenseSourceEmbed, embedded, match.URL, match.Confidence
+				return match.ID, nil
+			}
+		}
+	}
+
+	// Look for a LICENSE* file in the package directory (or parents)
+	licenseFile, distance, fileMatch, fileErr := p.findLicenseFileId(o)
+	if errors.Cause(fileErr) == ErrNoLicense && p.Module != nil {
+		// The extracted module may lack the license file; check the module zip in the download cache
+		licenseFile, fileMatch, fileErr = findModuleZipLicense(p.Module, o.MinConfidence, o.corpus)
+	}
+	fileSource := LicenseSourceFile
+	if cause := errors.Cause(fileErr); (cause == ErrNoLicense || cause == ErrLowConfidence) && o.ScanReadme {
+		if readme, readmeDistance, readmeMatch, err := findReadmeLicense(p.Dir, p.moduleDir(), o.MinConfidence, o.corpus); err == nil {
+			licenseFile, distance, fileMatch, fileErr, fileSource = readme, readmeDistance, readmeMatch, nil, LicenseSourceReadme
+		}
+	}
+	fileId := fileMatch.ID
+	if err != nil {
+		if fileErr != nil {
+			return "", errors.Wrapf(fileErr, "finding license file for %s", p.ImportPath)
+		}
+		p.licenseSource, p.licenseFile, p.licenseDistance, p.licenseURL = fileSource, licenseFile, distance, fileMatch.URL
+		p.licenseConfidence = fileMatch.Confidence
+		return fileId, nil
+	}
+
+	p.licenseSource = LicenseSourceHeader
+	if fileErr != nil || fileId == headerId {
+		return headerId, nil
+	}
+
+	// The headers and the license file disagree
+	conflict := fmt.Sprintf("source headers have %s but %s has %s", headerId, licenseFile, fileId)
+	if p.licenseConflict != "" {
+		conflict = p.licenseConflict + "; " + conflict
+	}
+	p.licenseConflict = conflict
+	useFile := false
+	switch o.ConflictPolicy {
+	case "prefer-file":
+		useFile = true
+	case "most-restrictive":
+		useFile = licenseRank(fileId) > licenseRank(headerId)
+	case "error":
+		return "", errors.Errorf("conflicting licenses for %s: %s", p.ImportPath, p.licenseConflict)
+	}
+	if useFile {
+		p.licenseSource, p.licenseFile, p.licenseDistance, p.licenseURL = fileSource, licenseFile, distance, fileMatch.URL
+		p.licenseConfidence = fileMatch.Confidence
+		return fileId, nil
+	}
+	return headerId, nil
+}
+
+// findLicenseFileId finds the license file for the package directory and returns its license. The license file of
+// the root of a module in the module cache is looked up once for all its packages. With Options.Log, the directories
+// that were looked in and the license file's matches are logged.
+func (p *Package) findLicenseFileId(o *Options) (string, int, licenseMatch, error) {
+	lookup := findLicenseFileCached
+	if module := p.moduleKey(); module != "" {
+		moduleDir := filepath.Clean(p.moduleDir())
+		lookup = func(dir string) (string, error) {
+			if filepath.Clean(dir) == moduleDir {
+				return findModuleLicenseFileCached(module, dir)
+			}
+			return findLicenseFileCached(dir)
+		}
+	}
+	find := lookup
+	if o.Log != nil {
+		find = func(dir string) (string, error) {
+			file, err := lookup(dir)
+			if err == nil {
+				o.Log.Info("license file lookup", "package", p.ImportPath, "dir", dir, "file", filepath.Base(file))
+			} else {
+				o.Log.Info("license file lookup", "package", p.ImportPath, "dir", dir, "err", err)
+			}
+			return file, err
+		}
+	}
+	licenseFile, distance, err := walkUp(p.Dir, p.moduleDir(), find)
+	if err != nil {
+		return "", 0, licenseMatch{}, err
+	}
+	match, err := readLicenseFileCached(licenseFile, o.MinConfidence, o.corpus)
+	if o.Log != nil {
+		p.logLicenseFile(o, licenseFile, match, err)
+	}
+	if match.cached {
+		p.licenseCached = append(p.licenseCached, "license of "+licenseFile+" from the license cache")
+	}
+	if err != nil {
+		return "", 0, licenseMatch{}, err
+	}
+	return licenseFile, distance, match, nil
+}
+
+// licenseRank returns how restrictive the license is, by the order of its category; licenses that aren't in the
+// classification table rank like permissive ones
+func licenseRank(lic string) int {
+	category := Category(lic)
+	if category == CategoryUnknown {
+		category = CategoryPermissive
+	}
+	return slices.Index(categoryOrder, category)
+}
+
+// findLicenseHeaders returns the license of the files from their SPDX-License-Identifier tags or license headers; if
+// the files have different licenses, these are combined with AND (sorted, so the result is deterministic), and the
+// license of each file is returned too
+func findLicenseHeaders(dir string, files []string, lc *licenseCorpus) (string, map[string]string, error) {
+	fileLicenses := map[string]string{}
+	exprs := []string{}
+	for _, file := range files {
+		// Use the SPDX tag if there is one, which is much faster than scanning the file
+		tag, err := readSPDXTag(filepath.Join(dir, file))
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "reading %s", file)
+		}
+		if tag == "" {
+			match, err := readLicenseHeader(filepath.Join(dir, file), lc)
+			if err != nil {
+				return "", nil, err // bail on first error (eg. file without license)
+			}
+			// A file with several license headers is taken to be dual licensed; files with different licenses all apply
+			tag = match.ID
+		}
+		fileLicenses[file] = tag
+		exprs = appendUnique(exprs, tag)
+	}
+	if len(exprs) == 0 {
+		return "", nil, ErrNoLicense
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil, nil
+	}
+	return andLicenses(exprs), fileLicenses, nil
+}
+
+// mixedLicenses describes the different licenses of the files, most used license first, with the files of the other
+// licenses; "" if they all have the same license
+func mixedLicenses(fileLicenses map[string]string) string {
+	filesOf := map[string][]string{}
+	for _, file := range sortedKeys(fileLicenses) {
+		filesOf[fileLicenses[file]] = append(filesOf[fileLicenses[file]], file)
+	}
+	if len(filesOf) < 2 {
+		return ""
+	}
+	exprs := sortedKeys(filesOf)
+	sort.SliceStable(exprs, func(i, j int) bool { return len(filesOf[exprs[i]]) > len(filesOf[exprs[j]]) })
+	var licenses []string
+	for i, expr := range exprs {
+		files := filesOf[expr]
+		n := fmt.Sprintf("%d files", len(files))
+		if len(files) == 1 {
+			n = "1 file"
+		}
+		if i > 0 {
+			n += ": " + strings.Join(files, ", ")
+		}
+		licenses = append(licenses, fmt.Sprintf("%s (%s)", expr, n))
+	}
+	return "source files have different licenses: " + strings.Join(licenses, ", ")
+}
+
+// ReadLicenseFile returns the distinct IDs of the built-in licenses in licenseFile, sorted, ignoring matches that
+// cover less than minConfidence percent of the text
+func ReadLicenseFile(licenseFile string, minConfidence int) ([]string, error) {
+	match, err := readLicense(licenseFile, minConfidence, nil)
+	return match.IDs, err
+}
+
+// readLicenseHeader detects the license headers in a source file; since most of a source file is code, the
+// confidence of the matches is not checked
+func readLicenseHeader(file string, lc *licenseCorpus) (licenseMatch, error) {
+	return readLicense(file, 0, lc)
+}
+
+func readLicense(licenseFile string, minConfidence int, lc *licenseCorpus) (licenseMatch, error) {
+	license, err := os.ReadFile(licenseFile)
+	if err != nil {
+		return licenseMatch{}, errors.Wrapf(err, "reading license file %s", licenseFile)
+	}
+	return scanLicense(license, licenseFile, minConfidence, lc)
+}
+
+// scanLicense detects the license in the contents of licenseFile. Matches with a confidence below minConfidence
+// (in percent) are ignored; see matchConfidence.
+func scanLicense(license []byte, licenseFile string, minConfidence int, lc *licenseCorpus) (licenseMatch, error) {
+	if len(bytes.TrimSpace(license)) == 0 {
+		return licenseMatch{}, errors.Wrapf(ErrEmptyLicense, "scanning license file %s", licenseFile)
+	}
+	cov := scanLicenseText(license, lc)
+	if len(cov.Match) == 0 {
+		return licenseMatch{}, errors.Wrapf(ErrNoLicense, "scanning license file %s", licenseFile)
+	}
+
+	var match licenseMatch
+	var weakest licensecheck.Match // best match below minConfidence
+	weakConfidence := -1
+	for _, m := range cov.Match {
+		if isUnknownLicenseId(m.ID) {
+			continue
+		}
+		confidence := matchConfidence(license, cov.Match, m)
+		if confidence < minConfidence {
+			if confidence > weakConfidence {
+				weakest, weakConfidence = m, confidence
+			}
+			continue
+		}
+		if match.Confidence == nil {
+			match.Confidence = map[string]int{}
+		}
+		match.Confidence[m.ID] = max(match.Confidence[m.ID], confidence)
+		match.IDs = appendUnique(match.IDs, m.ID)
+		if m.IsURL && match.URL == "" {
+			match.URL = string(license[m.Start:m.End])
+		}
+	}
+	if len(match.IDs) == 0 {
+		if weakConfidence >= 0 {
+			return licenseMatch{}, errors.Wrapf(ErrLowConfidence, "scanning license file %s: best match %s covers %d%%, below -min-confidence %d%%",
+				licenseFile, weakest.ID, weakConfidence, minConfidence)
+		}
+		return licenseMatch{}, errors.Wrapf(ErrUnknownLicense, "scanning license file %s", licenseFile)
+	}
+	sort.Strings(match.IDs)
+	match.ID = orLicenses(match.IDs)
+	return match, nil
+}
+
+// matchConfidence returns the percentage of text that's covered by match m, not counting the text covered by the
+// other matches and whitespace. This is per match on purpose: the coverage of all matches (Coverage.Percent) can be
+// high when most of the file is one license, while another match is just a stray line that resembles a license.
+// Dual licensed files are not penalized, since the other license's text is not counted.
+func matchConfidence(text []byte, matches []licensecheck.Match, m licensecheck.Match) int {
+	covered := make([]bool, len(text))
+	for _, other := range matches {
+		if other != m {
+			for i := other.Start; i < other.End; i++ {
+				covered[i] = true
+			}
+		}
+	}
+	var matched, total int
+	for i, c := range text {
+		if covered[i] || unicode.IsSpace(rune(c)) {
+			continue
+		}
+		total++
+		if i >= m.Start && i < m.End {
+			matched++
+		}
+	}
+	if total == 0 {
+		return 100
+	}
+	return matched * 100 / total
+}
+
+// isUnknownLicenseId reports whether licensecheck matched license text without identifying it.
+// Note that Match.Type can't be used for this: most built-in licenses have type licensecheck.Unknown.
+func isUnknownLicenseId(id string) bool {
+	return id == "" || strings.EqualFold(id, "unknown")
+}
+
+// Package represents a Go package. This (partial) definition is copied from the `go help list` command.
+type Package struct {
+	Dir        string   // directory containing package sources
+	ImportPath string   // import path of package in dir
+	Imports    []string // import paths used by this package
+	ForTest    string   // package is only for use in named test
+	Deps       []string // all (recursively) imported dependencies
+	Standard   bool     // is this package part of the standard Go library?
+	DepOnly    bool     // package is only a dependency, not explicitly listed
+	GoFiles    []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
+	CgoFiles   []string // .go source files that import "C"
+	CFiles     []string // .c source files
+	CXXFiles   []string // .cc, .cxx and .cpp source files
+	HFiles     []string // .h, .hh, .hpp and .hxx source files
+	Module     *Module  // info about package's containing module, if any (can be nil)
+
+	license         string
+	licenseSource   LicenseSource
+	licenseFile     string            // license file the license was read from, if any
+	licenseConflict string            // description of conflicting source headers and license file, if any
+	fileLicenses    map[string]string // license of each source file, if they have different licenses
+	bundled         []BundledLicense  // licenses of bundled C code that differ from the package's, if it uses cgo
+	licenseURL      string            // URL by which the license was identified, if any
+	licenseDistance int               // number of directories above Dir where the license file was found
+
+	licenseConfidence map[string]int // matchConfidence of each license ID in licenseFile
+	licenseExpression string         // detected dual license expression, if license is the branch that was chosen
+	licenseCached     []string       // caches the license was read from instead of detecting it, for -explain
+
+	platforms       []string            // platforms the package is listed for, with several Options.Platforms
+	importPlatforms map[string][]string // platforms of each import, with several Options.Platforms
+	depPlatforms    map[string][]string // platforms of each dependency, with several Options.Platforms
+}
+
+// LicenseSource is where the license of a package was found
+type LicenseSource string
+
+const (
+	LicenseSourceHeader   LicenseSource = "header"   // license headers in the source files
+	LicenseSourceFile     LicenseSource = "file"     // LICENSE (or similar) file
+	LicenseSourceReuse    LicenseSource = "reuse"    // REUSE .reuse/dep5 file
+	LicenseSourceEmbed    LicenseSource = "embed"    // license file embedded with //go:embed
+	LicenseSourceReview   LicenseSource = "review"   // no license detected, but reviewed in the config file
+	LicenseSourceOverride LicenseSource = "override" // from Options.Overrides
+	LicenseSourceReadme   LicenseSource = "readme"   // NOTICE or README file (-scan-readme)
+	LicenseSourceIndex    LicenseSource = "index"    // no license detected, but deps.dev has one (-index-fallback)
+	LicenseSourceVCS      LicenseSource = "vcs"      // license file above the module in its repository (-vcs-fallback)
+)
+
+// Module represents a Go module. This (partial) definition is copied from the `go help list` command.
+type Module struct {
+	Path      string  // module path
+	Version   string  // module version
+	Replace   *Module // replaced by this module
+	Main      bool    // is this the main module?
+	Dir       string  // directory holding files for this module, if any
+	GoVersion string  // go version used in module
+}
+
+// getPackageDependencies returns a list of dependencies for the packages in dir (or the given go list args), including their paths and directories
+func getPackageDependencies(ctx context.Context, dir string, shards int, env []string, args ...string) ([]Package, error) {
+	if shards > 1 {
+		return getPackageDependenciesSharded(ctx, dir, shards, env, args...)
+	}
+	return goListDeps(ctx, dir, env, args...)
+}
+
+// goListDeps runs go list -deps in dir, with the extra environment variables env (eg. GOOS)
+func goListDeps(ctx context.Context, dir string, env []string, args ...string) ([]Package, error) {
+	out, err := runGo(ctx, dir, env, append([]string{"list", "-deps", "-json"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := decodePackages(bytes.NewReader(out))
+	return packages, errors.Wrap(err, "decoding the go list output")
+}
+
+// splitArgs splits the go list arguments into flags and package patterns
+func splitArgs(args []string) (flags, patterns []string) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			flags = append(flags, arg)
+		} else {
+			patterns = append(patterns, arg)
+		}
+	}
+	return flags, patterns
+}
+
+// withoutTestFlag returns the flags without -test, for go list commands that don't list (only) packages, like go list -m
+func withoutTestFlag(flags []string) []string {
+	return slices.DeleteFunc(slices.Clone(flags), func(flag string) bool { return flag == "-test" })
+}
+
+// Scan checks the licenses of the packages in dir (selected by opts.Args) and their dependencies
+func Scan(dir string, opts Options) (*ModuleReport, error) {
+	return ScanContext(context.Background(), dir, opts)
+}
+
+// ScanContext is Scan, stopping the go commands when ctx is done; Options.Timeout still applies
+func ScanContext(ctx context.Context, dir string, opts Options) (*ModuleReport, error) {
+	o := &opts
+	if err := o.useLicenses(); err != nil {
+		return nil, err
+	}
+
+	// Step 1: Get the list of dependencies
+	args := o.goListArgs()
+	ctx, cancel := withTimeout(withGoCommandLog(ctx, o), o.Timeout)
+	defer cancel()
+	var deps []Package
+	var workspace []string
+	var err error
+	var vendored map[string]string
+	if o.Vendor {
+		if vendored, err = readVendorModules(dir); err != nil {
+			return nil, err
+		}
+	}
+	if o.PackageList != nil {
+		if deps, err = readPackageList(o.PackageList); err != nil {
+			return nil, errors.Wrap(err, "reading the package list")
+		}
+	} else {
+		if args, workspace, err = workspaceArgs(ctx, dir, args); err != nil {
+			return nil, errors.Wrapf(timeoutError(err, o.Timeout), "listing workspace modules of %s", dir)
+		}
+		if o.IncludeTools {
+			args = withTools(dir, args)
+		}
+		if deps, err = getPlatformDependencies(ctx, dir, o.Shards, o.Platforms, args...); err != nil {
+			return nil, errors.Wrapf(timeoutError(err, o.Timeout), "listing dependencies of %s", dir)
+		}
+	}
+	if len(o.Entrypoints) > 0 {
+		if deps, err = reachableFromEntrypoints(deps, dir, o.Entrypoints); err != nil {
+			return nil, err
+		}
+	}
+	if o.downloadSources() {
+		if err := downloadMissingSources(ctx, deps, o); err != nil {
+			return nil, errors.Wrap(timeoutError(err, o.Timeout), "downloading missing modules")
+		}
+	}
+	if o.PackageList != nil {
+		if err := checkPackageDirs(deps, o); err != nil {
+			return nil, errors.Wrap(err, "reading the package list")
+		}
+	}
+
+	// Step 2: Iterate over dependencies and read LICENSE file
+	byImportPath := map[ImportPath]*Package{}
+	depOf := map[ImportPath][]ImportPath{}
+	importOf := map[ImportPath][]ImportPath{}
+
+	for _, dep := range deps {
+		// make a copy of dep on the heap
+		pdep := new(Package)
+		*pdep = dep
+		importPath := normalizeImportPath(dep.ImportPath)
+		if o.isExcluded(importPath) {
+			continue // not even a dependency of the other packages
+		}
+		byImportPath[importPath] = pdep
+		if o.isSkipped(pdep) {
+			continue
+		}
+		for _, d := range dep.Deps {
+			pkg := normalizeImportPath(d)
+			depOf[pkg] = append(depOf[pkg], importPath)
+		}
+		for _, d := range dep.Imports {
+			pkg := normalizeImportPath(d)
+			importOf[pkg] = append(importOf[pkg], importPath)
+		}
+	}
+
+	if vendored != nil {
+		setVendorModuleDirs(byImportPath, vendored)
+	}
+
+	mainModules := map[string]bool{}
+	if o.MainModule != "enforce" {
+		if o.PackageList != nil {
+			mainModules = mainModulesOf(deps)
+		} else if mainModules, err = listMainModules(ctx, dir, args...); err != nil {
+			return nil, errors.Wrapf(timeoutError(err, o.Timeout), "listing main modules of %s", dir)
+		}
+	}
+
+	// Step 3: Check for license compatibility
+	report := &ModuleReport{Dir: dir, Workspace: workspace}
+	depLicense := func(pkg ImportPath) (string, string, bool) {
+		p := byImportPath[pkg]
+		if p == nil || o.isSkipped(p) {
+			return "", "", false
+		}
+		depLic, _ := p.FindLicense(o)
+		return depLic, p.licenseURL, true
+	}
+	if o.Incremental != "" {
+		if o.previousScan, err = loadScanState(o.Incremental, o); err != nil {
+			return nil, err
+		}
+		if o.goSums, err = readGoSums(dir, byImportPath); err != nil {
+			return nil, err
+		}
+	}
+	resetLicenseDirCache()
+	counts := cacheCounts()
+	resolveLicenses(byImportPath, o)
+	if o.Incremental != "" {
+		if err := nextScanState(byImportPath, o).save(o.Incremental); err != nil {
+			return nil, err
+		}
+	}
+	if o.indexFallback() {
+		if err := saveIndexLicenseCache(); err != nil {
+			return nil, err
+		}
+	}
+	if o.Log != nil {
+		after := cacheCounts()
+		o.Log.Info("license caches", "moduleHits", after[5]-counts[5], "moduleMisses", after[6]-counts[6], "dirHits", after[0]-counts[0],
+			"dirReads", after[1]-counts[1], "fileHits", after[2]-counts[2], "fileScans", after[3]-counts[3], "headerHits", after[4]-counts[4])
+	}
+	var scopes map[ImportPath]Scope
+	if o.usesScopes() {
+		scopes = packageScopes(byImportPath, goModTools(dir), o.Config.Plugins)
+	}
+	scoped := o.scopedOptions()
+	for _, importPath := range sortedImportPaths(byImportPath) {
+		p := byImportPath[importPath]
+		res := checkPackage(importPath, p, depLicense, scoped[scopes[importPath]])
+		if p.Module != nil && mainModules[p.Module.Path] {
+			report.addMain(res, o.MainModule)
+		} else {
+			report.add(res)
+		}
+	}
+	report.setImportedBy(importOf)
+	if err := o.stopOnError(report); err != nil {
+		return nil, err
+	}
+	if scopes != nil {
+		report.setScopes(scopes)
+	}
+	if o.ModuleLicense {
+		issues, warnings := checkModuleLicenses(byImportPath, depLicense, o)
+		report.Issues = append(report.Issues, issues...)
+		report.Warnings = append(report.Warnings, warnings...)
+	}
+
+	roots := rootPackages(byImportPath)
+	if o.Trace || o.Why != "" {
+		if o.Trace {
+			traceDeniedImports(report.Issues, importOf, roots)
+		}
+		if o.Why != "" {
+			report.Why = findWhy(o.Why, byImportPath, importOf, roots)
+		}
+	}
+
+	if len(o.Config.Rules) > 0 {
+		if err := o.Config.applyRules(report, importOf); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.CrossCheck {
+		issues, err := crossCheck(report.Packages)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	if o.RequireNotices != "" {
+		issues, err := checkNotices(report, o.RequireNotices)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	if o.Remediate {
+		remediate(ctx, report, byImportPath, o)
+	}
+
+	report.LicenseFiles = groupLicenseFiles(byImportPath)
+	report.DuplicateModules = findDuplicateModules(report.Packages)
+	for _, dup := range report.DuplicateModules {
+		if dup.LicensesDiffer() {
+			report.Warnings = append(report.Warnings, Issue{Kind: IssueVersionLicense, ImportPath: ImportPath(dup.Path), Message: dup.String()})
+		}
+	}
+	report.Issues, report.Warnings = o.Config.applySeverities(report.Issues, report.Warnings)
+	if o.Explain != "" {
+		scope := scopes[normalizeImportPath(o.Explain)]
+		report.Explain = explain(o.Explain, report, byImportPath, importOf, roots, scope, scoped[scope])
+	}
+
+	return report, nil
+}
+
+// rootPackages returns the listed packages, where the import chains start
+func rootPackages(byImportPath map[ImportPath]*Package) map[ImportPath]bool {
+	roots := map[ImportPath]bool{}
+	for importPath, p := range byImportPath {
+		if !p.DepOnly && p.ForTest == "" {
+			roots[importPath] = true
+		}
+	}
+	return roots
+}
+
+// resolveLicenses finds the licenses of all packages concurrently, with Options.Jobs workers, so checking them (which
+// also needs the licenses of their imports) doesn't have to wait on reading and scanning license files one by one
+func resolveLicenses(byImportPath map[ImportPath]*Package, o *Options) {
+	jobs := o.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	packages := make(chan *Package)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range packages {
+				p.FindLicense(o)
+			}
+		}()
+	}
+	for _, p := range byImportPath {
+		packages <- p
+	}
+	close(packages)
+	wg.Wait()
+}
+
+// packageResult is the outcome of checking a single package
+type packageResult struct {
+	pkg      *PackageReport // nil for standard and test packages
+	issues   []Issue
+	warnings []Issue
+	errors   []Issue // IssueScanError
+}
+
+// setModule sets the module of the issues and warnings
+func (r *packageResult) setModule(module string) {
+	for i := range r.issues {
+		r.issues[i].Module = module
+	}
+	for i := range r.warnings {
+		r.warnings[i].Module = module
+	}
+	for i := range r.errors {
+		r.errors[i].Module = module
+	}
+}
+
+// setReplace sets the replacement of the package's module on the issues and warnings
+func (r *packageResult) setReplace(replace string) {
+	for i := range r.issues {
+		r.issues[i].Replace = replace
+	}
+	for i := range r.warnings {
+		r.warnings[i].Replace = replace
+	}
+	for i := range r.errors {
+		r.errors[i].Replace = replace
+	}
+}
+
+func (r *ModuleReport) add(res packageResult) {
+	if res.pkg != nil {
+		r.Packages = append(r.Packages, *res.pkg)
+	}
+	r.Issues = append(r.Issues, res.issues...)
+	r.Warnings = append(r.Warnings, res.warnings...)
+	r.Errors = append(r.Errors, res.errors...)
+}
+
+// checkPackage checks a single package; depLicense returns the license (and license URL) of an imported package,
+// or false if it's a standard or test package
+func checkPackage(importPath ImportPath, p *Package, depLicense func(ImportPath) (string, string, bool), o *Options) (res packageResult) {
+	if p.Module != nil {
+		defer res.setModule(p.Module.Path)
+	}
+	if replace := p.replacement(); replace != "" {
+		defer res.setReplace(replace)
+	}
+	defer res.setPlatforms(p, o)
+	lic, err := p.FindLicense(o)
+	if !o.isSkipped(p) {
+		pr := PackageReport{ImportPath: importPath, Dir: p.Dir, License: lic, Category: expressionCategories(lic), LicenseURL: p.licenseURL}
+		if err == nil {
+			pr.Source = p.licenseSource
+			pr.LicenseFile = p.licenseFile
+			pr.Confidence = p.licenseConfidence
+			pr.Expression = p.licenseExpression
+		}
+		if o.Log != nil {
+			if err != nil {
+				o.Log.Info("license", "package", importPath, "err", err)
+			} else if p.licenseFile != "" {
+				o.Log.Info("license", "package", importPath, "license", lic, "source", p.licenseSource, "file", p.licenseFile,
+					"confidence", p.licenseConfidence)
+			} else {
+				o.Log.Info("license", "package", importPath, "license", lic, "source", p.licenseSource)
+			}
+		}
+		if p.Module != nil {
+			pr.Module, pr.Version, pr.ModuleDir, pr.Replace = p.Module.Path, p.Module.Version, p.moduleDir(), p.replacement()
+			pr.RepoURL = o.repoURL(p.Module.Path)
+		} else {
+			pr.RepoURL = o.repoURL(string(importPath))
+		}
+		if p.Dir != "" {
+			pr.NoticeFile, pr.NoticeHash = findNotice(p.Dir, p.moduleDir())
+		}
+		if err != nil {
+			pr.Error = err.Error()
+		}
+		if o.Graph {
+			for _, imp := range p.Imports {
+				if pkg := normalizeImportPath(imp); !o.isIgnored(pkg) {
+					if _, _, ok := depLicense(pkg); ok {
+						pr.Imports = append(pr.Imports, pkg)
+					}
+				}
+			}
+		}
+		pr.Verdict = o.verdict(importPath, lic, p.licenseURL, err)
+		res.pkg = &pr
+	}
+	if o.isIgnored(importPath) {
+		if res.pkg != nil {
+			res.pkg.Ignored = true
+		}
+		return res // neither checked itself nor reported as a denied import
+	}
+	if p.licenseConflict != "" && res.pkg != nil {
+		res.pkg.Conflict, res.pkg.FileLicenses = p.licenseConflict, p.fileLicenses
+		res.warnings = append(res.warnings, Issue{Kind: IssueLicenseConflict, ImportPath: importPath, License: lic, Message: p.licenseConflict})
+	}
+	if review := o.Config.findReview(importPath); review != nil && res.pkg != nil {
+		res.pkg.Review = review
+		if err == nil && lic != review.License {
+			res.issues = append(res.issues, Issue{Kind: IssueNeedsReview, ImportPath: importPath, License: lic,
+				Message: fmt.Sprintf("reviewed as %s by %s on %s", review.License, review.Reviewer, review.Date)})
+		}
+	}
+	switch errors.Cause(err) {
+	case ErrEmptyLicense:
+		res.warnings = append(res.warnings, Issue{Kind: IssueEmptyLicense, ImportPath: importPath, Message: err.Error()})
+	case ErrNotInModuleCache:
+		res.warnings = append(res.warnings, Issue{Kind: IssueNotInModCache, ImportPath: importPath, Message: err.Error()})
+	case ErrLowConfidence:
+		res.warnings = append(res.warnings, Issue{Kind: IssueLowConfidence, ImportPath: importPath, Message: err.Error()})
+	}
+	if isScanError(err) && !o.isSkipped(p) {
+		res.errors = append(res.errors, Issue{Kind: IssueScanError, ImportPath: importPath, License: UnknownLicense, Message: err.Error()})
+	} else if err != nil && (o.FailUnknown || o.Config.FailUnknown || len(o.Config.Allow) > 0) && !o.Config.isAcceptedUnknown(importPath) {
+		res.issues = append(res.issues, Issue{Kind: IssueUnknownLicense, ImportPath: importPath, Message: err.Error()})
+	}
+	if err == nil && res.pkg != nil && o.Config.isWarned(lic) {
+		res.warnings = append(res.warnings, Issue{Kind: IssueWarnedLicense, ImportPath: importPath, License: lic})
+	}
+	if err == nil && res.pkg != nil && !o.Config.isAllowed(lic, p.licenseURL) {
+		res.issues = append(res.issues, Issue{Kind: IssueNotAllowed, ImportPath: importPath, License: lic})
+	}
+	if o.MaxDistance >= 0 && p.licenseDistance > o.MaxDistance {
+		issue := Issue{Kind: IssueLicenseDistance, ImportPath: importPath, License: lic, Distance: p.licenseDistance}
+		if o.FailDistance {
+			res.issues = append(res.issues, issue)
+		} else {
+			res.warnings = append(res.warnings, issue)
+		}
+	}
+	if res.pkg != nil && err == nil {
+		p.bundled = p.findBundledLicenses(lic, o.MinConfidence, o.corpus)
+		res.pkg.Bundled = p.bundled
+		for _, bundled := range bundledLicenses(p.bundled) {
+			var verdict string
+			switch {
+			case o.isDenied(bundled, ""):
+				verdict = "denied"
+			case !o.Config.isAllowed(bundled, ""):
+				verdict = "not allowed"
+			default:
+				continue
+			}
+			res.issues = append(res.issues, Issue{Kind: IssueBundledLicense, ImportPath: importPath, License: lic,
+				Message: fmt.Sprintf("%s (%s) in %s", bundled, verdict, bundledFiles(p.bundled, bundled))})
+		}
+	}
+	if o.DetectLinkname && !o.isSkipped(p) {
+		if files, err := findLinknames(p.Dir, p.sourceFiles()); err == nil && len(files) > 0 {
+			res.warnings = append(res.warnings, Issue{Kind: IssueLinkname, ImportPath: importPath, License: lic, Message: strings.Join(files, ", ")})
+		}
+	}
+	if o.isDenied(lic, p.licenseURL) {
+		return res
+	}
+
+	deps := p.Imports
+	if o.Transitive {
+		deps = p.Deps
+	}
+	matrix := o.compatibilityMatrix()
+	var denied, incompatible []Dependency
+	for _, imp := range deps {
+		pkg := normalizeImportPath(imp)
+		if o.isIgnored(pkg) {
+			continue
+		}
+		depLic, depURL, ok := depLicense(pkg)
+		if !ok {
+			continue
+		}
+		dep := Dependency{ImportPath: pkg, License: depLic, Indirect: o.Transitive && !slices.Contains(p.Imports, imp)}
+		if o.isDenied(depLic, depURL) {
+			denied = append(denied, dep)
+		} else if matrix != nil {
+			if dep.Rule = incompatibility(matrix, lic, depLic); dep.Rule != "" {
+				incompatible = append(incompatible, dep)
+			}
+		}
+	}
+	if len(denied) > 0 {
+		res.issues = append(res.issues, Issue{Kind: IssueDeniedImport, ImportPath: importPath, License: lic, Imports: denied})
+	}
+	if len(incompatible) > 0 {
+		res.issues = append(res.issues, Issue{Kind: IssueIncompatible, ImportPath: importPath, License: lic, Imports: incompatible})
+	}
+	return res
+}
+
+// formatConfidence formats the confidence of each license ID of a license file match, eg. ", MIT 98%"
+func formatConfidence(confidence map[string]int) string {
+	var s string
+	for _, id := range sortedKeys(confidence) {
+		s += fmt.Sprintf(", %s %d%%", id, confidence[id])
+	}
+	return s
+}
+
+// verdict returns the outcome of the policy for the license of a package (or the error finding it)
+func (o *Options) verdict(importPath ImportPath, lic, url string, err error) Verdict {
+	switch {
+	case o.isIgnored(importPath):
+		return VerdictIgnored
+	case err != nil:
+		return VerdictUnknown
+	case o.isDenied(lic, url):
+		return VerdictDenied
+	case !o.Config.isAllowed(lic, url):
+		return VerdictNotAllowed
+	case o.Config.isWarned(lic):
+		return VerdictWarned
+	}
+	return VerdictAllowed
+}
+
+// setImportedBy sets the (non-skipped) packages that directly import each package of the report
+func (r *ModuleReport) setImportedBy(importOf map[ImportPath][]ImportPath) {
+	for _, packages := range [][]PackageReport{r.Packages, r.MainPackages} {
+		for i := range packages {
+			for _, importer := range importOf[packages[i].ImportPath] {
+				if !slices.Contains(packages[i].ImportedBy, importer) {
+					packages[i].ImportedBy = append(packages[i].ImportedBy, importer)
+				}
+			}
+			slices.Sort(packages[i].ImportedBy)
+		}
+	}
+}
+
+// setScopes sets the scope of each package of the report, and of the package of each issue
+func (r *ModuleReport) setScopes(scopes map[ImportPath]Scope) {
+	for _, packages := range [][]PackageReport{r.Packages, r.MainPackages} {
+		for i := range packages {
+			packages[i].Scope = scopes[packages[i].ImportPath]
+		}
+	}
+	for _, issues := range [][]Issue{r.Issues, r.Warnings} {
+		for i := range issues {
+			issues[i].Scope = scopes[issues[i].ImportPath]
+		}
+	}
+}
+
+// groupLicenseFiles returns each license file that was used and the packages it covers
+func groupLicenseFiles(byImportPath map[ImportPath]*Package) []LicenseFile {
+	byFile := map[string]*LicenseFile{}
+	var files []string
+	for _, importPath := range sortedImportPaths(byImportPath) {
+		p := byImportPath[importPath]
+		if p.licenseFile == "" || p.license == "" {
+			continue
+		}
+		lf := byFile[p.licenseFile]
+		if lf == nil {
+			lf = &LicenseFile{Path: p.licenseFile, License: p.license}
+			byFile[p.licenseFile] = lf
+			files = append(files, p.licenseFile)
+		}
+		lf.Packages = append(lf.Packages, importPath)
+	}
+	sort.Strings(files)
+	licenseFiles := make([]LicenseFile, len(files))
+	for i, file := range files {
+		licenseFiles[i] = *byFile[file]
+	}
+	return licenseFiles
+}
+
+// majorVersionSuffix matches the major version suffix of a module path, like "/v2" or gopkg.in's ".v2"
+var majorVersionSuffix = regexp.MustCompile(`(/|\.)v[0-9]+$`)
+
+// logicalModulePath returns the module path without its major version suffix
+func logicalModulePath(modulePath string) string {
+	return majorVersionSuffix.ReplaceAllString(modulePath, "")
+}
+
+// findDuplicateModules returns the modules that are used with more than one major version
+func findDuplicateModules(packages []PackageReport) []DuplicateModule {
+	byLogicalPath := map[string][]ModuleVersion{}
+	seen := map[string]bool{}
+	for _, pr := range packages {
+		if pr.Module == "" || seen[pr.Module] {
+			continue
+		}
+		seen[pr.Module] = true
+		logical := logicalModulePath(pr.Module)
+		byLogicalPath[logical] = append(byLogicalPath[logical], ModuleVersion{Path: pr.Module, Version: pr.Version, License: pr.License})
+	}
+
+	var dups []DuplicateModule
+	for logical, versions := range byLogicalPath {
+		if len(versions) > 1 {
+			dups = append(dups, DuplicateModule{Path: logical, Versions: versions})
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i].Path < dups[j].Path })
+	return dups
+}
@@ -0,0 +1,300 @@
+package licenseguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+const mitLicense = `MIT License
+
+Copyright (c) 2024 Example Authors
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+// writeFiles writes the files (by slash-separated path) in dir
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestConcurrentLicenseScans resolves the licenses of packages that share the LICENSE file of their module root in
+// several scans at once, which share the license cache; run with -race
+func TestConcurrentLicenseScans(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "example.com", "shared@v1.0.0") // like the module cache
+	const packages = 8
+	files := map[string]string{"LICENSE": mitLicense}
+	for i := 0; i < packages; i++ {
+		files[fmt.Sprintf("pkg%d/pkg.go", i)] = fmt.Sprintf("package pkg%d\n", i)
+	}
+	writeFiles(t, root, files)
+	before := cacheCounts()
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8)) // so the workers overlap, also on a single CPU
+	opts := DefaultOptions()
+	opts.Jobs = 8
+	const scans = 4
+	results := make([]map[ImportPath]*Package, scans)
+	var wg sync.WaitGroup
+	for i := range results {
+		byImportPath := map[ImportPath]*Package{}
+		for j := 0; j < packages; j++ {
+			name := fmt.Sprintf("pkg%d", j)
+			importPath := ImportPath("example.com/shared/" + name)
+			byImportPath[importPath] = &Package{
+				Dir:        filepath.Join(root, name),
+				ImportPath: string(importPath),
+				GoFiles:    []string{"pkg.go"},
+				Module:     &Module{Path: "example.com/shared", Version: "v1.0.0", Dir: root},
+			}
+		}
+		results[i] = byImportPath
+		wg.Add(1)
+		go func(byImportPath map[ImportPath]*Package) {
+			defer wg.Done()
+			resolveLicenses(byImportPath, &opts)
+		}(byImportPath)
+	}
+	wg.Wait()
+	for _, byImportPath := range results {
+		for importPath, p := range byImportPath {
+			if p.license != "MIT" {
+				t.Errorf("%s: got %q; want MIT", importPath, p.license)
+			}
+		}
+	}
+	// The workers that find the LICENSE while it's being scanned wait for the result instead of scanning it again
+	if n := cacheCounts()[3] - before[3]; n != 1 {
+		t.Errorf("the shared LICENSE was scanned %d times; want 1", n)
+	}
+}
+
+// TestFindLicenseMixedHeaders checks that the license of a package whose files have different license headers is the
+// same on every run, whatever the order of the files
+func TestFindLicenseMixedHeaders(t *testing.T) {
+	const (
+		mit    = "// SPDX-License-Identifier: MIT\n\npackage mixed\n"
+		apache = "// SPDX-License-Identifier: Apache-2.0\n\npackage mixed\n"
+	)
+	tests := []struct {
+		name         string
+		files        map[string]string
+		goFiles      []string
+		wantLicense  string
+		wantConflict string
+	}{
+		{
+			name:        "same license",
+			files:       map[string]string{"a.go": mit, "b.go": mit},
+			goFiles:     []string{"a.go", "b.go"},
+			wantLicense: "MIT",
+		},
+		{
+			name:         "two licenses",
+			files:        map[string]string{"a.go": mit, "b.go": apache},
+			goFiles:      []string{"a.go", "b.go"},
+			wantLicense:  "Apache-2.0 AND MIT",
+			wantConflict: "source files have different licenses: Apache-2.0 (1 file), MIT (1 file: a.go)",
+		},
+		{
+			name:         "two licenses in the other order",
+			files:        map[string]string{"a.go": mit, "b.go": apache},
+			goFiles:      []string{"b.go", "a.go"},
+			wantLicense:  "Apache-2.0 AND MIT",
+			wantConflict: "source files have different licenses: Apache-2.0 (1 file), MIT (1 file: a.go)",
+		},
+		{
+			name:         "most used license first",
+			files:        map[string]string{"a.go": apache, "b.go": mit, "c.go": mit},
+			goFiles:      []string{"a.go", "b.go", "c.go"},
+			wantLicense:  "Apache-2.0 AND MIT",
+			wantConflict: "source files have different licenses: MIT (2 files), Apache-2.0 (1 file: a.go)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			opts := DefaultOptions()
+			for run := 0; run < 10; run++ {
+				p := &Package{Dir: dir, ImportPath: "example.com/mixed", GoFiles: tt.goFiles, Module: &Module{Path: "example.com/mixed", Dir: dir}}
+				lic, err := p.FindLicense(&opts)
+				if err != nil {
+					t.Fatalf("run %d: %v", run, err)
+				}
+				if lic != tt.wantLicense || p.licenseConflict != tt.wantConflict {
+					t.Fatalf("run %d: got %q (%q); want %q (%q)", run, lic, p.licenseConflict, tt.wantLicense, tt.wantConflict)
+				}
+			}
+		})
+	}
+}
+
+func TestIsAcceptedException(t *testing.T) {
+	o := DefaultOptions()
+	o.AcceptExceptions = []string{"classpath-exception-2.0", ""}
+	o.Config.AcceptExceptions = []string{" ", "GCC-exception-3.1"}
+	tests := []struct {
+		exception string
+		want      bool
+	}{
+		{"Classpath-exception-2.0", true},
+		{"GCC-exception-3.1", true},
+		{"GCC-exception-2.0", false},
+		{"Classpath-exception", false},
+		{"LLVM-exception", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := o.isAcceptedException(tt.exception); got != tt.want {
+			t.Errorf("isAcceptedException(%q) = %v; want %v", tt.exception, got, tt.want)
+		}
+	}
+}
+
+// TestCustomLicenseScans scans a license file with and without a custom license at the same time: the custom licenses
+// of one scan's configuration don't change what the other scans detect
+func TestCustomLicenseScans(t *testing.T) {
+	const acme = "This software is the confidential property of Acme Widgets and may only be used under a written agreement with Acme Widgets"
+	file := filepath.Join(t.TempDir(), "LICENSE")
+	writeFiles(t, filepath.Dir(file), map[string]string{"LICENSE": acme + "\n"})
+	custom := DefaultOptions()
+	custom.Config.Licenses = []CustomLicense{{ID: "LicenseRef-Acme", Text: acme}}
+	builtin := DefaultOptions()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		o, want := builtin, ""
+		if i%2 == 0 {
+			o, want = custom, "LicenseRef-Acme"
+		}
+		wg.Add(1)
+		go func(o Options, want string) {
+			defer wg.Done()
+			if err := o.useLicenses(); err != nil {
+				t.Error(err)
+				return
+			}
+			for run := 0; run < 10; run++ {
+				if match, _ := readLicense(file, 0, o.corpus); match.ID != want {
+					t.Errorf("got %q; want %q", match.ID, want)
+					return
+				}
+			}
+		}(o, want)
+	}
+	wg.Wait()
+}
+
+// TestModuleLicenseCache looks up the license file of the root of a module in the module cache once for all its
+// packages, also in later scans, while a package with its own license file still gets that one
+func TestModuleLicenseCache(t *testing.T) {
+	bsd, err := os.ReadFile(filepath.Join("testdata", "vendored", "vendor", "example.com", "bsd", "LICENSE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modcache := t.TempDir()
+	t.Setenv("GOMODCACHE", modcache)
+	root := filepath.Join(modcache, "example.com", "cached@v1.0.0")
+	writeFiles(t, root, map[string]string{
+		"LICENSE":    mitLicense,
+		"a/a.go":     "package a\n",
+		"b/b.go":     "package b\n",
+		"c/LICENSE":  string(bsd),
+		"c/c.go":     "package c\n",
+		"c/d/d.go":   "package d\n",
+		"e/f/g/g.go": "package g\n",
+	})
+	moduleLicenseCacheMu.Lock()
+	delete(moduleLicenseCache, "example.com/cached@v1.0.0") // from an earlier run, with -count
+	moduleLicenseCacheMu.Unlock()
+	want := map[string]string{"a": "MIT", "b": "MIT", "c": "BSD-3-Clause", "c/d": "BSD-3-Clause", "e/f/g": "MIT"}
+	opts := DefaultOptions()
+	for scan := 0; scan < 2; scan++ {
+		resetLicenseDirCache()
+		before := cacheCounts()
+		for _, rel := range sortedKeys(want) {
+			p := &Package{
+				Dir:        filepath.Join(root, filepath.FromSlash(rel)),
+				ImportPath: "example.com/cached/" + rel,
+				GoFiles:    []string{filepath.Base(rel) + ".go"},
+				Module:     &Module{Path: "example.com/cached", Version: "v1.0.0", Dir: root},
+			}
+			if lic, err := p.FindLicense(&opts); err != nil || lic != want[rel] {
+				t.Errorf("scan %d: %s: got %q, %v; want %s", scan, rel, lic, err, want[rel])
+			}
+		}
+		after := cacheCounts()
+		hits, misses := after[5]-before[5], after[6]-before[6]
+		wantHits, wantMisses := int64(2), int64(1) // the first of a, b and e/f/g looks it up in the first scan
+		if scan > 0 {
+			wantHits, wantMisses = 3, 0
+		}
+		if hits != wantHits || misses != wantMisses {
+			t.Errorf("scan %d: got %d module root lookups cached and %d looked up; want %d and %d", scan, hits, misses, wantHits, wantMisses)
+		}
+	}
+}
+
+// TestLicenseLog logs the license file of a package with its license as attributes, and the licensecheck matches at the
+// debug level only
+func TestLicenseLog(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"LICENSE": mitLicense, "a.go": "package a\n"})
+	for _, level := range []slog.Level{slog.LevelInfo, slog.LevelDebug} {
+		var out bytes.Buffer
+		opts := DefaultOptions()
+		opts.Log = slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: level}))
+		opts.LogMatches = true
+		p := &Package{Dir: dir, ImportPath: "example.com/a", GoFiles: []string{"a.go"}, Module: &Module{Path: "example.com/a", Dir: dir}}
+		if _, err := p.FindLicense(&opts); err != nil {
+			t.Fatal(err)
+		}
+		var found, matches bool
+		for decoder := json.NewDecoder(&out); decoder.More(); {
+			var record map[string]any
+			if err := decoder.Decode(&record); err != nil {
+				t.Fatal(err)
+			}
+			switch record["msg"] {
+			case "license file":
+				found = record["package"] == "example.com/a" && record["file"] == filepath.Join(dir, "LICENSE") && record["license"] == "MIT"
+			case "licensecheck match":
+				matches = true
+			}
+		}
+		if !found || matches != (level == slog.LevelDebug) {
+			t.Errorf("%v: got the license file %v and matches %v", level, found, matches)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package licenseguard
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// findLinknames returns the files that contain a //go:linkname directive, which can pull in code across license
+// boundaries without showing up as an import
+func findLinknames(dir string, files []string) ([]string, error) {
+	var found []string
+	for _, file := range files {
+		ok, err := hasLinkname(filepath.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, file)
+		}
+	}
+	return found, nil
+}
+
+func hasLinkname(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "//go:linkname ") {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,40 @@
+package licenseguard
+
+import (
+	"context"
+	"strings"
+)
+
+// listMainModules returns the paths of the main module(s) in dir, as reported by go list -m
+func listMainModules(ctx context.Context, dir string, args ...string) (map[string]bool, error) {
+	flags, _ := splitArgs(args)
+	flags = withoutTestFlag(flags)
+	out, err := runGo(ctx, dir, nil, append(append([]string{"list", "-m"}, flags...), "-f", "{{.Path}}")...)
+	if err != nil {
+		return nil, err
+	}
+	mainModules := map[string]bool{}
+	for _, path := range strings.Fields(string(out)) {
+		mainModules[path] = true
+	}
+	return mainModules, nil
+}
+
+// addMain adds the result of checking one of the main module's own packages, according to -main-module. Denied
+// imports are always reported, since those are about the dependencies, not about the main module itself.
+func (r *ModuleReport) addMain(res packageResult, mainModule string) {
+	for _, issue := range res.issues {
+		if issue.Kind == IssueDeniedImport {
+			r.Issues = append(r.Issues, issue)
+		} else if mainModule == "report-separately" {
+			r.Warnings = append(r.Warnings, issue)
+		}
+	}
+	r.Errors = append(r.Errors, res.errors...)
+	if mainModule == "report-separately" {
+		if res.pkg != nil {
+			r.MainPackages = append(r.MainPackages, *res.pkg)
+		}
+		r.Warnings = append(r.Warnings, res.warnings...)
+	}
+}
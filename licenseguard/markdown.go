@@ -0,0 +1,123 @@
+package licenseguard
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+)
+
+// licenseModules returns the module versions (path@version) that have each license, sorted
+func licenseModules(report Report) map[string][]string {
+	byLicense := map[string][]string{}
+	for _, ml := range SummarizeModules(report) {
+		name := ml.Path
+		if ml.Version != "" {
+			name += "@" + ml.Version
+		}
+		for _, lic := range ml.Licenses {
+			byLicense[lic] = append(byLicense[lic], name)
+		}
+	}
+	return byLicense
+}
+
+// reportIssues returns the text of the issues or warnings of all modules in the report, prefixed like WriteText
+func reportIssues(report Report, warnings bool) []string {
+	var texts []string
+	for _, m := range report.Modules {
+		issues := m.Issues
+		if warnings {
+			issues = m.Warnings
+		}
+		for _, issue := range issues {
+			var prefix string
+			if len(m.Workspace) > 1 && slices.Contains(m.Workspace, issue.Module) {
+				prefix = "[" + issue.Module + "] "
+			}
+			if issue.Scope != ScopeBuild {
+				prefix += "[" + string(issue.Scope) + "] "
+			}
+			texts = append(texts, prefix+issue.text())
+		}
+	}
+	return texts
+}
+
+// markdownEscaper escapes the characters that would be taken as formatting in a Markdown table or list
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "<", "&lt;")
+
+// WriteMarkdown writes the report as a Markdown document, with a table of the licenses by number of packages, the
+// modules that have each license and the violations and warnings
+func WriteMarkdown(w io.Writer, report Report) error {
+	summary := Summarize(report)
+	fmt.Fprintln(w, "# License report")
+	fmt.Fprintln(w, "\n## Licenses")
+	fmt.Fprintln(w, "\n| License | Packages | Modules |")
+	fmt.Fprintln(w, "| --- | ---: | ---: |")
+	for _, ls := range summary {
+		fmt.Fprintf(w, "| %s | %d | %d |\n", markdownEscaper.Replace(ls.License), len(ls.Packages), len(ls.Modules))
+	}
+	fmt.Fprintln(w, "\n## Modules by license")
+	for _, ls := range summary {
+		fmt.Fprintf(w, "\n### %s\n\n", markdownEscaper.Replace(ls.License))
+		for _, module := range ls.Modules {
+			fmt.Fprintf(w, "- %s\n", markdownEscaper.Replace(module))
+		}
+	}
+	writeMarkdownIssues(w, "Violations", reportIssues(report, false))
+	writeMarkdownIssues(w, "Warnings", reportIssues(report, true))
+	return nil
+}
+
+func writeMarkdownIssues(w io.Writer, title string, texts []string) {
+	fmt.Fprintf(w, "\n## %s\n\n", title)
+	if len(texts) == 0 {
+		fmt.Fprintln(w, "None.")
+		return
+	}
+	fmt.Fprintln(w, "```")
+	for _, text := range texts {
+		fmt.Fprintln(w, strings.ReplaceAll(text, "```", "'''"))
+	}
+	fmt.Fprintln(w, "```")
+}
+
+// WriteHTML writes the report as a standalone HTML page, with the same content as WriteMarkdown
+func WriteHTML(w io.Writer, report Report) error {
+	summary := Summarize(report)
+	fmt.Fprintln(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>License report</title>\n</head>\n<body>")
+	fmt.Fprintln(w, "<h1>License report</h1>")
+	fmt.Fprintln(w, "<h2>Licenses</h2>")
+	fmt.Fprintln(w, "<table>\n<tr><th>License</th><th>Packages</th><th>Modules</th></tr>")
+	for _, ls := range summary {
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%d</td><td>%d</td></tr>\n", html.EscapeString(ls.License), len(ls.Packages), len(ls.Modules))
+	}
+	fmt.Fprintln(w, "</table>")
+	fmt.Fprintln(w, "<h2>Modules by license</h2>")
+	for _, ls := range summary {
+		fmt.Fprintf(w, "<h3>%s</h3>\n<ul>\n", html.EscapeString(ls.License))
+		for _, module := range ls.Modules {
+			fmt.Fprintf(w, "<li>%s</li>\n", html.EscapeString(module))
+		}
+		fmt.Fprintln(w, "</ul>")
+	}
+	writeHTMLIssues(w, "Violations", reportIssues(report, false))
+	writeHTMLIssues(w, "Warnings", reportIssues(report, true))
+	_, err := fmt.Fprintln(w, "</body>\n</html>")
+	return err
+}
+
+func writeHTMLIssues(w io.Writer, title string, texts []string) {
+	fmt.Fprintf(w, "<h2>%s</h2>\n", title)
+	if len(texts) == 0 {
+		fmt.Fprintln(w, "<p>None.</p>")
+		return
+	}
+	fmt.Fprintln(w, "<pre>")
+	for _, text := range texts {
+		fmt.Fprintln(w, html.EscapeString(text))
+	}
+	fmt.Fprintln(w, "</pre>")
+}
@@ -0,0 +1,161 @@
+package licenseguard
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Metrics accumulates the metrics of scans, to export them in the Prometheus text format (which OpenMetrics
+// scrapers also read): the scan durations, the packages scanned, the findings by severity and license, and the
+// license caches. It's safe for concurrent use.
+type Metrics struct {
+	mu          sync.Mutex
+	scans       int64
+	failed      int64 // scans that returned an error, or modules that could not be checked
+	seconds     float64
+	lastSeconds float64
+	packages    int64
+	scanErrors  int64                    // packages that could not be scanned (-keep-going)
+	findings    map[metricsFinding]int64 // issues and warnings
+	licenses    map[string]int64         // packages scanned by license
+}
+
+// metricsFinding are the labels of a finding
+type metricsFinding struct {
+	severity Severity
+	kind     IssueKind
+	license  string
+}
+
+// NewMetrics returns metrics without any scans
+func NewMetrics() *Metrics {
+	return &Metrics{findings: map[metricsFinding]int64{}, licenses: map[string]int64{}}
+}
+
+// Observe adds a scan that took elapsed, with its report, or the error that it failed with. The license of a finding
+// is the license of the denied (or incompatible) import, if any, so each import of an issue is a finding.
+func (m *Metrics) Observe(report Report, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans++
+	m.seconds += elapsed.Seconds()
+	m.lastSeconds = elapsed.Seconds()
+	if err != nil {
+		m.failed++
+		return
+	}
+	for _, mr := range report.Modules {
+		if mr.Error != "" {
+			m.failed++
+		}
+		for _, pr := range slices.Concat(mr.MainPackages, mr.Packages) {
+			m.packages++
+			m.licenses[cmp.Or(pr.License, UnknownLicense)]++
+		}
+		m.scanErrors += int64(len(mr.Errors))
+		add := func(issues []Issue, severity Severity) {
+			for _, issue := range issues {
+				severity := cmp.Or(issue.Severity, severity)
+				if len(issue.Imports) == 0 {
+					m.findings[metricsFinding{severity, issue.Kind, cmp.Or(issue.License, UnknownLicense)}]++
+				}
+				for _, d := range issue.Imports {
+					m.findings[metricsFinding{severity, issue.Kind, cmp.Or(d.License, UnknownLicense)}]++
+				}
+			}
+		}
+		add(mr.Issues, SeverityError)
+		add(mr.Warnings, SeverityWarning)
+	}
+}
+
+// metricsLabel escapes a label value of the Prometheus text format
+var metricsLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Write writes the metrics in the Prometheus text format
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	metric := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+	metric("golicenseguard_scan_duration_seconds", "summary", "Duration of the scans.")
+	fmt.Fprintf(&b, "golicenseguard_scan_duration_seconds_sum %g\ngolicenseguard_scan_duration_seconds_count %d\n", m.seconds, m.scans)
+	metric("golicenseguard_last_scan_duration_seconds", "gauge", "Duration of the last scan.")
+	fmt.Fprintf(&b, "golicenseguard_last_scan_duration_seconds %g\n", m.lastSeconds)
+	metric("golicenseguard_scans_failed_total", "counter", "Scans that failed, or modules that could not be checked.")
+	fmt.Fprintf(&b, "golicenseguard_scans_failed_total %d\n", m.failed)
+	metric("golicenseguard_packages_scanned_total", "counter", "Packages whose license was checked.")
+	fmt.Fprintf(&b, "golicenseguard_packages_scanned_total %d\n", m.packages)
+	metric("golicenseguard_packages_by_license_total", "counter", "Packages whose license was checked, by license.")
+	for _, license := range sortedKeys(m.licenses) {
+		fmt.Fprintf(&b, "golicenseguard_packages_by_license_total{license=\"%s\"} %d\n", metricsLabel.Replace(license), m.licenses[license])
+	}
+	metric("golicenseguard_package_scan_errors_total", "counter", "Packages that could not be scanned.")
+	fmt.Fprintf(&b, "golicenseguard_package_scan_errors_total %d\n", m.scanErrors)
+	metric("golicenseguard_findings_total", "counter", "Issues and warnings, by severity, kind and license.")
+	var findings []metricsFinding
+	for f := range m.findings {
+		findings = append(findings, f)
+	}
+	slices.SortFunc(findings, func(a, b metricsFinding) int {
+		return cmp.Or(cmp.Compare(a.severity, b.severity), cmp.Compare(a.kind, b.kind), cmp.Compare(a.license, b.license))
+	})
+	for _, f := range findings {
+		fmt.Fprintf(&b, "golicenseguard_findings_total{severity=\"%s\",kind=\"%s\",license=\"%s\"} %d\n",
+			f.severity, f.kind, metricsLabel.Replace(f.license), m.findings[f])
+	}
+
+	counts := cacheCounts()
+	hits := counts[0] + counts[2] + counts[4] + counts[5]
+	misses := counts[1] + counts[3] // a module root that isn't cached is looked up in the directory cache
+	metric("golicenseguard_cache_hits_total", "counter", "License lookups answered from the caches, by cache.")
+	fmt.Fprintf(&b, "golicenseguard_cache_hits_total{cache=\"directory\"} %d\n", counts[0])
+	fmt.Fprintf(&b, "golicenseguard_cache_hits_total{cache=\"license\"} %d\n", counts[2])
+	fmt.Fprintf(&b, "golicenseguard_cache_hits_total{cache=\"header\"} %d\n", counts[4])
+	fmt.Fprintf(&b, "golicenseguard_cache_hits_total{cache=\"module\"} %d\n", counts[5])
+	metric("golicenseguard_cache_misses_total", "counter", "License lookups that were not answered from the caches, by cache: directories read, license files scanned and module roots looked up in the directory cache.")
+	fmt.Fprintf(&b, "golicenseguard_cache_misses_total{cache=\"directory\"} %d\n", counts[1])
+	fmt.Fprintf(&b, "golicenseguard_cache_misses_total{cache=\"license\"} %d\n", counts[3])
+	fmt.Fprintf(&b, "golicenseguard_cache_misses_total{cache=\"module\"} %d\n", counts[6])
+	metric("golicenseguard_cache_hit_ratio", "gauge", "Ratio of the license lookups that were answered from the caches.")
+	ratio := 0.0
+	if hits+misses > 0 {
+		ratio = float64(hits) / float64(hits+misses)
+	}
+	fmt.Fprintf(&b, "golicenseguard_cache_hit_ratio %g\n", ratio)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMetricsFile writes the metrics to the file, through a temporary file that replaces it, so a collector that
+// reads the file (like the textfile collector of the Prometheus node exporter) never sees a partial file
+func (m *Metrics) WriteMetricsFile(file string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil { // CreateTemp makes it readable only by the owner
+		tmp.Close()
+		return err
+	}
+	if err := m.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), file), "writing the metrics")
+}
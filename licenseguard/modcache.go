@@ -0,0 +1,85 @@
+package licenseguard
+
+import (
+	"archive/zip"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotInModuleCache is returned when a module from the module cache has no license file, which usually means the
+// license only exists in the root of the upstream repository (eg. for nested modules), not that it's unlicensed.
+var ErrNotInModuleCache = fmt.Errorf("license not present in module cache")
+
+// escapeModulePath escapes upper case letters like the module cache does, eg. "github.com/Foo" -> "github.com/!foo"
+func escapeModulePath(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// moduleCacheRoot returns the module cache directory, GOMODCACHE or GOPATH/pkg/mod
+func moduleCacheRoot() string {
+	if modcache := os.Getenv("GOMODCACHE"); modcache != "" {
+		return modcache
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+// findModuleZipLicense looks for a license file in the root of the module's zip in the module download cache. For a
+// vendored module (without a Dir), the extracted module in the module cache is checked first.
+func findModuleZipLicense(mod *Module, minConfidence int, lc *licenseCorpus) (string, licenseMatch, error) {
+	if mod.Version == "" || mod.Replace != nil {
+		return "", licenseMatch{}, ErrNoLicense // not from the module cache
+	}
+	escaped := escapeModulePath(mod.Path) + "@" + mod.Version
+	modcache := filepath.ToSlash(moduleCacheRoot())
+	if mod.Dir != "" {
+		var ok bool
+		if modcache, ok = strings.CutSuffix(filepath.ToSlash(mod.Dir), "/"+escaped); !ok {
+			return "", licenseMatch{}, ErrNoLicense
+		}
+	} else if licenseFile, err := findLicenseFile(filepath.Join(filepath.FromSlash(modcache), filepath.FromSlash(escaped))); err == nil {
+		match, err := readLicenseFileCached(licenseFile, minConfidence, lc)
+		return licenseFile, match, err
+	}
+	zipFile := filepath.Join(filepath.FromSlash(modcache), "cache", "download", filepath.FromSlash(escapeModulePath(mod.Path)), "@v", mod.Version+".zip")
+
+	r, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return "", licenseMatch{}, errors.Wrapf(ErrNotInModuleCache, "module %s", escaped)
+	}
+	defer r.Close()
+	prefix := mod.Path + "@" + mod.Version + "/"
+	for _, f := range r.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || strings.Contains(name, "/") || !isLicenseFile(strings.ToLower(name)) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", licenseMatch{}, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", licenseMatch{}, err
+		}
+		licenseFile := zipFile + "!" + name
+		match, err := scanLicense(data, licenseFile, minConfidence, lc)
+		return licenseFile, match, err
+	}
+	return "", licenseMatch{}, errors.Wrapf(ErrNotInModuleCache, "module %s", escaped)
+}
@@ -0,0 +1,83 @@
+package licenseguard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ModuleLicenses is a module version with the licenses of its packages and the issues found in them
+type ModuleLicenses struct {
+	Path     string       `json:"path"`
+	Version  string       `json:"version,omitempty"`
+	Licenses []string     `json:"licenses"` // distinct licenses of the packages, sorted
+	Packages []ImportPath `json:"packages"`
+	Issues   []Issue      `json:"issues,omitempty"`
+}
+
+// SummarizeModules groups the packages of all modules in the report by the module (and version) they're from, sorted
+// by module path. Packages outside of any module (eg. with GOPATH) are grouped under their import path.
+func SummarizeModules(report Report) []ModuleLicenses {
+	byModule := map[string]*ModuleLicenses{}
+	moduleOf := map[ImportPath]*ModuleLicenses{}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			if moduleOf[pr.ImportPath] != nil {
+				continue // same package in more than one module
+			}
+			path := pr.Module
+			if path == "" {
+				path = string(pr.ImportPath)
+			}
+			key := path + "@" + pr.Version
+			ml := byModule[key]
+			if ml == nil {
+				ml = &ModuleLicenses{Path: path, Version: pr.Version}
+				byModule[key] = ml
+			}
+			ml.Packages = append(ml.Packages, pr.ImportPath)
+			ml.Licenses = appendUnique(ml.Licenses, pr.License)
+			moduleOf[pr.ImportPath] = ml
+		}
+	}
+	for _, m := range report.Modules {
+		for _, issue := range m.Issues {
+			if ml := moduleOf[issue.ImportPath]; ml != nil {
+				ml.Issues = append(ml.Issues, issue)
+			}
+		}
+	}
+
+	modules := make([]ModuleLicenses, 0, len(byModule))
+	for _, ml := range byModule {
+		sort.Strings(ml.Licenses)
+		sort.Slice(ml.Packages, func(i, j int) bool { return ml.Packages[i] < ml.Packages[j] })
+		modules = append(modules, *ml)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if modules[i].Path != modules[j].Path {
+			return modules[i].Path < modules[j].Path
+		}
+		return modules[i].Version < modules[j].Version
+	})
+	return modules
+}
+
+// WriteModuleSummary writes each module version with its licenses, its number of packages and its issues
+func WriteModuleSummary(w io.Writer, modules []ModuleLicenses) {
+	for _, ml := range modules {
+		name := ml.Path
+		if ml.Version != "" {
+			name += "@" + ml.Version
+		}
+		n := fmt.Sprintf("%d packages", len(ml.Packages))
+		if len(ml.Packages) == 1 {
+			n = "1 package"
+		}
+		fmt.Fprintf(w, "%s: %s (%s)\n", name, strings.Join(ml.Licenses, ", "), n)
+		for _, issue := range ml.Issues {
+			fmt.Fprintf(w, "  %s\n", strings.ReplaceAll(strings.TrimRight(issue.text(), "\n"), "\n", "\n  "))
+		}
+	}
+}
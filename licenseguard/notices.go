@@ -0,0 +1,286 @@
+package licenseguard
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// moduleNotices is a dependency module with the license and NOTICE files of its packages
+type moduleNotices struct {
+	name         string // path@version
+	dir          string // module root, if known
+	licenses     []string
+	licenseFiles []string
+	noticeFiles  []string
+}
+
+// collectNotices returns the dependency modules of the report (not the main modules), sorted by path, with the license
+// and NOTICE files of their packages
+func collectNotices(report Report) []*moduleNotices {
+	byModule := map[string]*moduleNotices{}
+	for _, m := range report.Modules {
+		for _, pr := range m.Packages {
+			if pr.Module != "" && pr.Version == "" {
+				continue // main module
+			}
+			name := pr.Module
+			if name == "" {
+				name = string(pr.ImportPath)
+			} else {
+				name += "@" + pr.Version
+			}
+			mn := byModule[name]
+			if mn == nil {
+				mn = &moduleNotices{name: name, dir: pr.ModuleDir}
+				byModule[name] = mn
+			}
+			mn.licenses = appendUnique(mn.licenses, pr.License)
+			if pr.LicenseFile != "" {
+				mn.licenseFiles = appendUnique(mn.licenseFiles, pr.LicenseFile)
+			}
+			if notice, _, err := findFileUp(pr.Dir, pr.ModuleDir, isNoticeFile); err == nil {
+				mn.noticeFiles = appendUnique(mn.noticeFiles, notice)
+			}
+		}
+	}
+	modules := make([]*moduleNotices, 0, len(byModule))
+	for _, mn := range byModule {
+		modules = append(modules, mn)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].name < modules[j].name })
+	return modules
+}
+
+// readNoticeFile reads a license or NOTICE file, which can be an entry of a module zip ("file.zip!LICENSE")
+func readNoticeFile(file string) ([]byte, error) {
+	zipFile, name, ok := strings.Cut(file, ".zip!")
+	if !ok {
+		return os.ReadFile(file)
+	}
+	r, err := zip.OpenReader(zipFile + ".zip")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	version := filepath.Base(zipFile) // the zip is mod/@v/version.zip and its files are in path@version/
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, "@"+version+"/"+name) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// writeNotices writes the license texts and NOTICE files of all dependency modules, grouped by module
+func writeNotices(w io.Writer, report Report) error {
+	separator := strings.Repeat("=", 80)
+	for _, mn := range collectNotices(report) {
+		sort.Strings(mn.licenses)
+		fmt.Fprintf(w, "%s\n%s (%s)\n%s\n", separator, mn.name, strings.Join(mn.licenses, ", "), separator)
+		if len(mn.licenseFiles) == 0 {
+			fmt.Fprintln(w, "\nNo license file; the license is in the headers of the source files.")
+		}
+		for _, file := range append(mn.licenseFiles, mn.noticeFiles...) {
+			text, err := readNoticeFile(file)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "\n%s\n", bytes.TrimRight(text, "\n\r\t "))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// WriteNoticesFile writes the license texts and NOTICE files of all dependency modules of the report to file, to be
+// shipped with the binaries that use them
+func WriteNoticesFile(file string, report Report) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := writeNotices(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CopiedModule is an entry of the manifest.json written by CopyLicenses
+type CopiedModule struct {
+	Module   string   `json:"module"` // path@version
+	Licenses []string `json:"licenses"`
+	Files    []string `json:"files"` // copied license and NOTICE files, relative to the directory
+}
+
+// noticeFileName returns the name of a license or NOTICE file relative to its module root, eg. "LICENSE" or
+// "sub/COPYING", or its base name if it's not in the module root
+func noticeFileName(file, moduleDir string) string {
+	if _, name, ok := strings.Cut(file, ".zip!"); ok {
+		return name
+	}
+	if moduleDir != "" && isBelow(file, moduleDir) {
+		if rel, err := filepath.Rel(moduleDir, file); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.Base(file)
+}
+
+// CopyLicenses copies the license and NOTICE files of all dependency modules of the report verbatim into dir, as
+// dir/path@version/LICENSE, and writes a manifest.json listing the modules with their licenses and copied files
+func CopyLicenses(dir string, report Report) error {
+	manifest := []CopiedModule{}
+	for _, mn := range collectNotices(report) {
+		sort.Strings(mn.licenses)
+		cm := CopiedModule{Module: mn.name, Licenses: mn.licenses, Files: []string{}}
+		licenseFiles := mn.licenseFiles
+		if len(licenseFiles) == 0 && mn.dir != "" {
+			// The license is from the source headers; copy the license text of the module too, if it has one
+			if file, err := findLicenseFileCached(mn.dir); err == nil {
+				licenseFiles = []string{file}
+			}
+		}
+		for _, file := range append(licenseFiles, mn.noticeFiles...) {
+			text, err := readNoticeFile(file)
+			if err != nil {
+				return err
+			}
+			name := path.Join(mn.name, noticeFileName(file, mn.dir))
+			if slices.Contains(cm.Files, name) {
+				continue // the same file of a module version in the module cache and its zip
+			}
+			dest := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(dest, text, 0644); err != nil {
+				return err
+			}
+			cm.Files = append(cm.Files, name)
+		}
+		manifest = append(manifest, cm)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(data, '\n'), 0644)
+}
+
+var (
+	noticeHashes   = map[string]string{} // NOTICE file -> SHA-256 of its text
+	noticeHashesMu sync.Mutex
+)
+
+// findNotice returns the NOTICE file of the package directory or its parents up to the module root, and the SHA-256
+// of its text; "" if there's none
+func findNotice(dir, moduleDir string) (string, string) {
+	file, _, err := findFileUp(dir, moduleDir, isNoticeFile)
+	if err != nil {
+		return "", ""
+	}
+	noticeHashesMu.Lock()
+	defer noticeHashesMu.Unlock()
+	if hash, ok := noticeHashes[file]; ok {
+		return file, hash
+	}
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return "", ""
+	}
+	sum := sha256.Sum256(text)
+	noticeHashes[file] = hex.EncodeToString(sum[:])
+	return file, noticeHashes[file]
+}
+
+// requiresNotice reports whether one of the licenses of the expression requires redistributing the NOTICE file, like
+// Apache-2.0
+func requiresNotice(lic string) bool {
+	for _, term := range licenseTerms(lic) {
+		if obligations, _ := obligationsFor(term); slices.Contains(obligations, ObligationNotice) {
+			return true
+		}
+	}
+	return false
+}
+
+// readNoticeBundle reads the attribution bundle: a file, like the one written by -notices, or a directory, like the
+// one written by -copy-licenses, whose files are concatenated. The text is normalized by normalizeNotice.
+func readNoticeBundle(bundle string) (string, error) {
+	fi, err := os.Stat(bundle)
+	if err != nil {
+		return "", errors.Wrap(err, "reading the attribution bundle")
+	}
+	if !fi.IsDir() {
+		text, err := os.ReadFile(bundle)
+		return normalizeNotice(text), errors.Wrap(err, "reading the attribution bundle")
+	}
+	var sb strings.Builder
+	err = filepath.WalkDir(bundle, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(normalizeNotice(text))
+		sb.WriteByte(' ')
+		return nil
+	})
+	return sb.String(), errors.Wrap(err, "reading the attribution bundle")
+}
+
+// normalizeNotice collapses the whitespace of a NOTICE text, so it matches when it was rewrapped or reindented
+func normalizeNotice(text []byte) string {
+	return strings.Join(strings.Fields(string(text)), " ")
+}
+
+// checkNotices reports the NOTICE files of the dependencies with a license that requires redistributing them (like
+// Apache-2.0) whose text is not in the attribution bundle (-require-notices), once for each NOTICE file
+func checkNotices(report *ModuleReport, bundle string) ([]Issue, error) {
+	text, err := readNoticeBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	checked := map[string]bool{}
+	for _, pr := range report.Packages {
+		if pr.Module != "" && pr.Version == "" && pr.Replace == "" {
+			continue // main module
+		}
+		if pr.NoticeFile == "" || pr.Ignored || checked[pr.NoticeFile] || !requiresNotice(pr.License) {
+			continue
+		}
+		checked[pr.NoticeFile] = true
+		notice, err := os.ReadFile(pr.NoticeFile)
+		if err != nil {
+			return nil, err
+		}
+		if normalized := normalizeNotice(notice); normalized != "" && !strings.Contains(text, normalized) {
+			issues = append(issues, Issue{Kind: IssueMissingNotice, ImportPath: pr.ImportPath, License: pr.License, Module: pr.Module, Message: pr.NoticeFile})
+		}
+	}
+	return issues, nil
+}
@@ -0,0 +1,160 @@
+package licenseguard
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Obligation is something that must be done to comply with a license when distributing
+type Obligation string
+
+const (
+	ObligationLicenseText   Obligation = "Include the license text in the distribution"
+	ObligationNotice        Obligation = "Include the NOTICE file in the distribution"
+	ObligationStateChanges  Obligation = "Mark modified files with a prominent notice of the changes"
+	ObligationSourceChanges Obligation = "Provide the source code of modifications to the licensed files"
+	ObligationSourceOffer   Obligation = "Provide the complete corresponding source code (or a written source offer) of the program"
+	ObligationRelink        Obligation = "Allow users to replace the library (eg. provide object files or source for relinking)"
+	ObligationNetworkSource Obligation = "Offer the complete source code to users interacting with the program over a network"
+)
+
+// licenseObligations maps license ID prefixes to their obligations; the longest matching prefix wins
+var licenseObligations = map[string][]Obligation{
+	"0BSD":       nil,
+	"AGPL":       {ObligationLicenseText, ObligationSourceOffer, ObligationNetworkSource},
+	"Apache-2.0": {ObligationLicenseText, ObligationNotice, ObligationStateChanges},
+	"BSD":        {ObligationLicenseText},
+	"BSL-1.0":    {ObligationLicenseText},
+	"CC0":        nil,
+	"EPL":        {ObligationLicenseText, ObligationSourceChanges},
+	"GPL":        {ObligationLicenseText, ObligationSourceOffer, ObligationStateChanges},
+	"ISC":        {ObligationLicenseText},
+	"LGPL":       {ObligationLicenseText, ObligationSourceChanges, ObligationRelink},
+	"MIT":        {ObligationLicenseText},
+	"MPL":        {ObligationLicenseText, ObligationSourceChanges},
+	"SSPL":       {ObligationLicenseText, ObligationSourceOffer, ObligationNetworkSource},
+	"Unlicense":  nil,
+	"Zlib":       {ObligationStateChanges},
+}
+
+// obligationsFor returns the obligations for the license, or nil if unknown
+func obligationsFor(lic string) ([]Obligation, bool) {
+	lic, _ = splitException(lic)
+	var best string
+	for prefix := range licenseObligations {
+		if strings.HasPrefix(lic, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return nil, false
+	}
+	return licenseObligations[best], true
+}
+
+// writeChecklist writes a Markdown checklist of the obligations of all licenses in the report
+func writeChecklist(w io.Writer, report Report) {
+	modulesByObligation := map[Obligation]map[string]bool{}
+	var unknown []string
+	seen := map[string]bool{}
+	for _, m := range report.Modules {
+		for _, pr := range m.Packages {
+			module := pr.Module
+			if module == "" {
+				module = string(pr.ImportPath)
+			}
+			if seen[module] || pr.Module != "" && pr.Version == "" { // skip the main module
+				continue
+			}
+			seen[module] = true
+			obligations, ok := obligationsFor(pr.License)
+			if !ok {
+				unknown = append(unknown, fmt.Sprintf("%s (%s)", module, licenseOrUnknown(pr.License)))
+				continue
+			}
+			for _, o := range obligations {
+				if o == ObligationNotice && !hasNoticeFile(pr.Dir, pr.ModuleDir) {
+					continue
+				}
+				if modulesByObligation[o] == nil {
+					modulesByObligation[o] = map[string]bool{}
+				}
+				modulesByObligation[o][module] = true
+			}
+		}
+	}
+
+	obligations := make([]string, 0, len(modulesByObligation))
+	for o := range modulesByObligation {
+		obligations = append(obligations, string(o))
+	}
+	sort.Strings(obligations)
+
+	fmt.Fprintln(w, "# License compliance checklist")
+	for _, o := range obligations {
+		fmt.Fprintf(w, "\n- [ ] %s:\n", o)
+		for _, module := range sortedKeys(modulesByObligation[Obligation(o)]) {
+			fmt.Fprintf(w, "  - [ ] %s\n", module)
+		}
+	}
+	for _, m := range report.Modules {
+		if len(m.LicenseFiles) == 0 {
+			continue
+		}
+		fmt.Fprintln(w, "\n## License files to include")
+		for _, lf := range m.LicenseFiles {
+			fmt.Fprintf(w, "\n- [ ] %s (%s), covering:\n", lf.Path, lf.License)
+			for _, importPath := range lf.Packages {
+				fmt.Fprintf(w, "  - %s\n", importPath)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		fmt.Fprintln(w, "\n- [ ] Review the license of these modules manually:")
+		for _, module := range unknown {
+			fmt.Fprintf(w, "  - [ ] %s\n", module)
+		}
+	}
+}
+
+func licenseOrUnknown(lic string) string {
+	if lic == "" || lic == UnknownLicense {
+		return "unknown license"
+	}
+	return lic
+}
+
+// hasNoticeFile reports whether dir or its module root contains a NOTICE file
+func hasNoticeFile(dir, moduleDir string) bool {
+	_, _, err := findFileUp(dir, moduleDir, isNoticeFile)
+	return err == nil
+}
+
+func isNoticeFile(lower string) bool {
+	return lower == "notice" || lower == "notice.txt" || lower == "notice.md"
+}
+
+func sortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// WriteChecklistFile writes a Markdown checklist of the license obligations of the report to file
+func WriteChecklistFile(file string, report Report) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	writeChecklist(f, report)
+	return f.Close()
+}
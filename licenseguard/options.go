@@ -0,0 +1,125 @@
+package licenseguard
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Options configures a Scan; use DefaultOptions for the defaults of the command line tool
+type Options struct {
+	Args             []string          // arguments for go list, ie. flags and package patterns
+	PackageList      io.Reader         // go list -deps -json output to use instead of running go list
+	Platforms        []Platform        // list the dependencies for each GOOS/GOARCH and check their union
+	Tags             string            // comma-separated build tags for go list
+	IncludeTests     bool              // also check the test packages and their dependencies
+	IncludeTools     bool              // also check the tools of go.mod tool directives and tools.go files
+	Vendor           bool              // list the packages in the vendor directory (-mod=vendor), without network access
+	NoDownload       bool              // don't download the modules of packages whose directory is missing
+	Config           Config            // allow list, reviews, etc. from the configuration file
+	Deny             []string          // license IDs (or substrings of IDs) or categories that are denied; DefaultDeny if empty
+	Ignore           []string          // import path patterns of packages that are not checked (but still reported)
+	Exclude          []string          // import path patterns of packages that are left out, as if they weren't listed
+	Entrypoints      []string          // package patterns of the commands that ship; only the packages they use are checked
+	AcceptExceptions []string          // license exceptions that make a denied license acceptable
+	Overrides        map[string]string // import path prefix (optionally @version constraint) -> license ID
+	RepoURLMap       map[string]string // module path prefix -> repository URL prefix
+	FailUnknown      bool              // report packages with an undetermined license as issues
+	MaxDistance      int               // warn when the license file is more than this many directories up; -1 to disable
+	FailDistance     bool              // report MaxDistance as an issue instead of a warning
+	ScanReadme       bool              // look for the license in NOTICE and README files when there's no license file
+	Compatibility    bool              // check imports against DefaultIncompatible, if the Config has no matrix
+	ModuleLicense    bool              // check all dependencies against the license of the main module, from its root
+	Transitive       bool              // check all dependencies of each package for denied licenses, not only its imports
+	DetectLinkname   bool              // warn about packages that use //go:linkname
+	ConflictPolicy   string            // prefer-header, prefer-file, most-restrictive or error
+	MainModule       string            // enforce, report-separately or skip
+	MinConfidence    int               // ignore license file matches below this percentage
+	Prefer           []string          // licenses to choose from dual licensed expressions, in order; see Config.Prefer
+	Shards           int               // number of concurrent go list invocations
+	Jobs             int               // number of packages whose license is found concurrently; 0 for GOMAXPROCS
+	Graph            bool              // set the (non-standard) imports of each PackageReport
+	Trace            bool              // set the import chain of denied imports
+	Why              string            // package or module to find the shortest import chain to, in ModuleReport.Why
+	Explain          string            // package to explain the verdict of, in ModuleReport.Explain
+	CrossCheck       bool              // compare the licenses with deps.dev (requires network)
+	IndexFallback    bool              // look up undetected licenses of modules on deps.dev (requires network)
+	VCSFallback      bool              // look for the license files of modules above the module in their repository
+	Remediate        bool              // suggest fixes for the violations, downloading the latest version of their modules
+	RequireNotices   string            // attribution bundle (file or directory) that must have the NOTICE files of Apache-2.0 dependencies
+	Offline          bool              // no network access: disables IndexFallback, also when set in the Config
+	Timeout          time.Duration     // maximum time for go list; 0 for no timeout
+	PullTimeout      time.Duration     // maximum time to pull an image (ScanImage); 0 for no timeout
+	Log              *slog.Logger      // if not nil, log how the license of each package was found, at the info level
+	LogMatches       bool              // with Log, also log the licensecheck matches of each license file, at the debug level
+	LogCommands      bool              // with Log, also log the go commands that are run, at the debug level
+	Incremental      string            // state file with the licenses of the previous scan, reused for unchanged modules
+	StopOnError      bool              // fail the scan when the files of a package can't be read, instead of reporting it in ModuleReport.Errors
+
+	previousScan *scanState        // loaded from Incremental
+	goSums       map[string]string // module@version -> go.sum hash, with Incremental
+	corpus       *licenseCorpus    // the custom licenses of the Config, if any; see useLicenses
+}
+
+// DefaultDeny is used when Options.Deny is empty
+var DefaultDeny = []string{"AGPL"}
+
+// DefaultOptions returns the default options
+func DefaultOptions() Options {
+	return Options{
+		MaxDistance:    -1,
+		ConflictPolicy: "prefer-header",
+		MainModule:     "enforce",
+		MinConfidence:  50, // not 75, which rejects the 64% Apache-2.0 notice in the LICENSE of gopkg.in/yaml.v3
+		Shards:         1,
+		Timeout:        2 * time.Minute,
+		PullTimeout:    10 * time.Minute,
+	}
+}
+
+// goListArgs returns the go list flags for the options, followed by the Args
+func (o *Options) goListArgs() []string {
+	var flags []string
+	if o.Tags != "" {
+		flags = append(flags, "-tags="+o.Tags)
+	}
+	if o.Vendor {
+		flags = append(flags, "-mod=vendor")
+	}
+	if o.IncludeTests && !slices.Contains(o.Args, "-test") {
+		flags = append(flags, "-test")
+	}
+	return append(flags, o.Args...)
+}
+
+// isSkipped reports whether the license of the package is not checked: standard library packages, and test packages
+// unless IncludeTests is set
+func (o *Options) isSkipped(p *Package) bool {
+	return p.Standard || p.ForTest != "" && !o.IncludeTests
+}
+
+// vcsFallback reports whether license files may be looked up in the repositories of modules
+func (o *Options) vcsFallback() bool {
+	return o.VCSFallback && !o.Offline
+}
+
+// downloadSources reports whether the modules of packages without a directory may be downloaded
+func (o *Options) downloadSources() bool {
+	return !o.NoDownload && !o.Vendor && !o.Offline
+}
+
+// indexFallback reports whether undetected licenses are looked up on deps.dev
+func (o *Options) indexFallback() bool {
+	return (o.IndexFallback || o.Config.IndexFallback) && !o.Offline
+}
+
+// stopOnError returns the first scan error of the report with StopOnError, to fail the scan with
+func (o *Options) stopOnError(report *ModuleReport) error {
+	if !o.StopOnError || len(report.Errors) == 0 {
+		return nil
+	}
+	return errors.Errorf("%s; use -keep-going to report it and continue", report.Errors[0].Message)
+}
@@ -0,0 +1,125 @@
+package licenseguard
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+// ORT (OSS Review Toolkit) analyzer result, limited to the fields golicenseguard can fill in. Go modules are
+// mapped to ORT packages with "Go::<module path>:<version>" identifiers; all dependencies of the main module end
+// up in a single flat "main" scope, since go list doesn't tell which module requires which.
+
+type ortResult struct {
+	Projects []ortProject `json:"projects"`
+	Packages []ortPackage `json:"packages"`
+}
+
+type ortProject struct {
+	Id                 string     `json:"id"`
+	DefinitionFilePath string     `json:"definition_file_path"`
+	DeclaredLicenses   []string   `json:"declared_licenses"`
+	Vcs                ortVcs     `json:"vcs"`
+	HomepageUrl        string     `json:"homepage_url"`
+	Scopes             []ortScope `json:"scopes"`
+}
+
+type ortScope struct {
+	Name         string          `json:"name"`
+	Dependencies []ortDependency `json:"dependencies"`
+}
+
+type ortDependency struct {
+	Id string `json:"id"`
+}
+
+type ortPackage struct {
+	Id               string   `json:"id"`
+	Purl             string   `json:"purl"`
+	DeclaredLicenses []string `json:"declared_licenses"`
+	Description      string   `json:"description"`
+	HomepageUrl      string   `json:"homepage_url"`
+	Vcs              ortVcs   `json:"vcs"`
+}
+
+type ortVcs struct {
+	Type     string `json:"type"`
+	Url      string `json:"url"`
+	Revision string `json:"revision"`
+	Path     string `json:"path"`
+}
+
+func ortId(modulePath, version string) string {
+	return "Go::" + modulePath + ":" + version
+}
+
+func newOrtVcs(repoURL string) ortVcs {
+	if repoURL == "" {
+		return ortVcs{}
+	}
+	return ortVcs{Type: "Git", Url: repoURL}
+}
+
+// newOrtResult converts the report to an ORT analyzer result, with one project per checked module
+func newOrtResult(report Report) ortResult {
+	var result ortResult
+	packages := map[string]*ortPackage{}
+	for _, m := range report.Modules {
+		var project *ortProject
+		deps := map[string]bool{}
+		for _, pr := range m.Packages {
+			if pr.Module == "" {
+				continue // not in a module
+			}
+			id := ortId(pr.Module, pr.Version)
+			if pr.Version == "" { // main module
+				if project == nil {
+					project = &ortProject{Id: id, DefinitionFilePath: "go.mod", Vcs: newOrtVcs(pr.RepoURL), HomepageUrl: pr.RepoURL}
+				}
+				if pr.License != UnknownLicense {
+					project.DeclaredLicenses = appendUnique(project.DeclaredLicenses, pr.License)
+				}
+				continue
+			}
+			deps[id] = true
+			pkg := packages[id]
+			if pkg == nil {
+				pkg = &ortPackage{Id: id, Purl: "pkg:golang/" + pr.Module + "@" + pr.Version, DeclaredLicenses: []string{},
+					HomepageUrl: pr.RepoURL, Vcs: newOrtVcs(pr.RepoURL)}
+				packages[id] = pkg
+			}
+			if pr.License != UnknownLicense {
+				pkg.DeclaredLicenses = appendUnique(pkg.DeclaredLicenses, pr.License)
+			}
+		}
+		if project == nil {
+			continue
+		}
+		scope := ortScope{Name: "main", Dependencies: []ortDependency{}}
+		for _, id := range sortedKeys(deps) {
+			scope.Dependencies = append(scope.Dependencies, ortDependency{Id: id})
+		}
+		project.Scopes = []ortScope{scope}
+		if project.DeclaredLicenses == nil {
+			project.DeclaredLicenses = []string{}
+		}
+		result.Projects = append(result.Projects, *project)
+	}
+
+	result.Packages = []ortPackage{}
+	for _, pkg := range packages {
+		sort.Strings(pkg.DeclaredLicenses)
+		result.Packages = append(result.Packages, *pkg)
+	}
+	sort.Slice(result.Packages, func(i, j int) bool { return result.Packages[i].Id < result.Packages[j].Id })
+	return result
+}
+
+// WriteOrtFile writes the report to file as an ORT analyzer result
+func WriteOrtFile(file string, report Report) error {
+	data, err := json.MarshalIndent(newOrtResult(report), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
@@ -0,0 +1,140 @@
+package licenseguard
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOverrides reads the overrides, which map import path prefixes to license IDs, from a YAML (or JSON) file
+func LoadOverrides(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading overrides %s", file)
+	}
+	overrides := map[string]string{}
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, errors.Wrapf(err, "parsing overrides %s", file)
+	}
+	if err := validateOverrides(overrides); err != nil {
+		return nil, errors.Wrapf(err, "overrides %s", file)
+	}
+	return overrides, nil
+}
+
+// validateOverrides checks the version constraints of the override keys
+func validateOverrides(overrides map[string]string) error {
+	for key := range overrides {
+		if _, constraint, ok := strings.Cut(key, "@"); ok {
+			if err := validateConstraint(constraint); err != nil {
+				return errors.Wrapf(err, "override %s", key)
+			}
+		}
+	}
+	return nil
+}
+
+// findOverride returns the license for the longest import path prefix in the overrides (or else in those of the
+// configuration file) that matches the module version, or "" if none matches
+func (o *Options) findOverride(importPath ImportPath, version string) string {
+	if lic := findOverride(o.Overrides, importPath, version); lic != "" {
+		return lic
+	}
+	return findOverride(o.Config.Overrides, importPath, version)
+}
+
+// findOverride returns the license of the longest matching prefix, which matches the import path and the paths below
+// it; a prefix can be followed by @ and a version constraint (eg. "github.com/foo/bar@<v2.0.0"), which wins over the
+// same prefix without one
+func findOverride(overrides map[string]string, importPath ImportPath, version string) string {
+	var best, bestPrefix string
+	for key := range overrides {
+		prefix, constraint, _ := strings.Cut(key, "@")
+		if !isPathPrefix(string(importPath), prefix) || !matchesVersion(version, constraint) {
+			continue
+		}
+		if best == "" || len(prefix) > len(bestPrefix) || len(prefix) == len(bestPrefix) && key > best {
+			best, bestPrefix = key, prefix
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return overrides[best]
+}
+
+// isPathPrefix reports whether the prefix is the path or one of its parents, so github.com/foo/bar is a prefix of
+// github.com/foo/bar/baz but not of github.com/foo/barbaz
+func isPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// parseComparison splits a comparison of a version constraint, like ">= v1.2.0", into its operator and version
+func parseComparison(c string) (string, string) {
+	c = strings.TrimSpace(c)
+	bound := strings.TrimLeft(c, "<>=")
+	return c[:len(c)-len(bound)], strings.TrimSpace(bound)
+}
+
+// validateConstraint checks that each comparison of the constraint has a known operator and a valid semantic version
+func validateConstraint(constraint string) error {
+	for _, c := range strings.Split(constraint, ",") {
+		op, bound := parseComparison(c)
+		switch op {
+		case "", "=", "==", "<", "<=", ">", ">=":
+		default:
+			return errors.Errorf("invalid operator %q in version constraint %q", op, constraint)
+		}
+		if !semver.IsValid(bound) {
+			return errors.Errorf("invalid version %q in version constraint %q", bound, constraint)
+		}
+	}
+	return nil
+}
+
+// matchesVersion reports whether version satisfies all of the comma-separated comparisons in the constraint (eg.
+// ">=v1.2.0,<v2"), which are compared as semantic versions; a version without an operator must be equal. An empty
+// constraint matches any version, and an invalid one none.
+func matchesVersion(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+	if version == "" {
+		return false // main module or GOPATH
+	}
+	for _, c := range strings.Split(constraint, ",") {
+		op, bound := parseComparison(c)
+		if !semver.IsValid(bound) {
+			return false
+		}
+		order := semver.Compare(version, bound)
+		switch op {
+		case "", "=", "==":
+			if order != 0 {
+				return false
+			}
+		case "<":
+			if order >= 0 {
+				return false
+			}
+		case "<=":
+			if order > 0 {
+				return false
+			}
+		case ">":
+			if order <= 0 {
+				return false
+			}
+		case ">=":
+			if order < 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package licenseguard
+
+import "testing"
+
+func TestMatchesVersion(t *testing.T) {
+	tests := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"v1.2.0", "", true},
+		{"", "", true},
+		{"", ">=v1.0.0", false},
+		{"v1.2.0", "v1.2.0", true},
+		{"v1.2.0", "=v1.2.0", true},
+		{"v1.2.1", "==v1.2.0", false},
+		{"v1.2.0", ">=v1.2.0,<v2", true},
+		{"v1.1.9", ">=v1.2.0,<v2", false},
+		{"v2.0.0", ">=v1.2.0,<v2", false},
+		{"v1.5.0", ">= v1.2.0, < v2", true},
+		{"v1.5.0", "<=v1.5.0", true},
+		{"v1.5.0", ">v1.5.0", false},
+		{"v1.5.0", "~v1.5.0", false},
+		{"v1.5.0", ">=garbage", false},
+		{"v1.2.3", "v1.2.3+incompatible", true},
+		{"v1.2.0", "v1.2", true},
+		{"v2.0.1", ">v2", true},
+		// pre-releases are compared by their dot-separated identifiers, numerically where they're numbers
+		{"v1.0.0-rc.10", ">v1.0.0-rc.9", true},
+		{"v1.0.0-rc.1", "<v1.0.0", true},
+		{"v1.0.0-alpha", "<v1.0.0-beta", true},
+		// pseudo-versions are pre-releases of the version after their base
+		{"v0.0.0-20240101000000-abcdefabcdef", "<v0.1.0", true},
+		{"v1.2.4-0.20240101000000-abcdefabcdef", ">v1.2.3", true},
+		{"v1.2.4-0.20240101000000-abcdefabcdef", "<v1.2.4", true},
+		{"v1.2.4-0.20240101000000-abcdefabcdef", ">v1.2.4-0.20230101000000-abcdefabcdef", true},
+	}
+	for _, tt := range tests {
+		if got := matchesVersion(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("matchesVersion(%q, %q) = %v; want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestFindOverride(t *testing.T) {
+	overrides := map[string]string{
+		"example.com/fork":                  "MIT",
+		"example.com/fork@>=v1.2.0,<v2":     "Apache-2.0",
+		"example.com/fork/internal":         "BSD-3-Clause",
+		"example.com/other@v0.1.0":          "ISC",
+		"example.com/fork/internal@<v1.0.0": "0BSD",
+		"github.com/mycorp/":                "Apache-2.0",
+	}
+	tests := []struct {
+		importPath ImportPath
+		version    string
+		want       string
+	}{
+		{"example.com/fork", "v1.0.0", "MIT"},
+		{"example.com/fork", "v1.2.0", "Apache-2.0"},
+		{"example.com/fork", "v2.0.0", "MIT"},
+		{"example.com/fork/pkg", "v1.3.0", "Apache-2.0"},
+		{"example.com/fork/internal/x", "v1.3.0", "BSD-3-Clause"},
+		{"example.com/fork/internal", "v0.9.0", "0BSD"},
+		{"example.com/other", "v0.1.0", "ISC"},
+		{"example.com/other", "v0.2.0", ""},
+		{"example.com/unrelated", "v1.0.0", ""},
+		// prefixes match whole path elements
+		{"example.com/forked", "v1.2.0", ""},
+		{"example.com/fork/internalx", "v0.9.0", "MIT"},
+		{"github.com/mycorp/app", "v1.0.0", "Apache-2.0"},
+		{"github.com/mycorporation/app", "v1.0.0", ""},
+	}
+	for _, tt := range tests {
+		if got := findOverride(overrides, tt.importPath, tt.version); got != tt.want {
+			t.Errorf("findOverride(%s@%s) = %q; want %q", tt.importPath, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestValidateOverrides(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"example.com/fork", true},
+		{"example.com/fork@v1.2.0", true},
+		{"example.com/fork@>=v1.2.0, <v2", true},
+		{"example.com/fork@<=v1.0.0-rc.1", true},
+		{"example.com/fork@1.2.0", false},
+		{"example.com/fork@>=v1.x", false},
+		{"example.com/fork@~v1.5.0", false},
+		{"example.com/fork@=>v1.5.0", false},
+		{"example.com/fork@", false},
+		{"example.com/fork@>=v1.2.0,", false},
+	}
+	for _, tt := range tests {
+		if err := validateOverrides(map[string]string{tt.key: "MIT"}); (err == nil) != tt.valid {
+			t.Errorf("validateOverrides(%s) = %v; want valid %v", tt.key, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package licenseguard
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// decodePackages decodes the output of go list -json
+func decodePackages(r io.Reader) ([]Package, error) {
+	decoder := json.NewDecoder(r)
+	var packages []Package
+	for {
+		var p Package
+		if err := decoder.Decode(&p); err == io.EOF {
+			return packages, nil
+		} else if err != nil {
+			return packages, err
+		}
+		packages = append(packages, p)
+	}
+}
+
+// readPackageList reads pre-generated go list -deps -json output (Options.PackageList) and checks that it has what's
+// needed to check the licenses: all dependencies (the directories of the packages are checked by checkPackageDirs,
+// after downloading the missing modules)
+func readPackageList(r io.Reader) ([]Package, error) {
+	packages, err := decodePackages(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding go list -json output")
+	}
+	listed := make(map[string]bool, len(packages))
+	for _, p := range packages {
+		listed[p.ImportPath] = true
+	}
+	for _, p := range packages {
+		if p.ImportPath == "" {
+			return nil, errors.New("package without ImportPath; not go list -json output?")
+		}
+		if p.Standard {
+			continue // don't need the GOROOT
+		}
+		if len(p.Imports) > 0 && len(p.Deps) == 0 {
+			return nil, errors.Errorf("package %s has Imports but no Deps; not go list -json output?", p.ImportPath)
+		}
+		for _, dep := range p.Deps {
+			if !listed[dep] {
+				return nil, errors.Errorf("dependency %s of package %s is not listed; use go list -deps -json", dep, p.ImportPath)
+			}
+		}
+	}
+	return packages, nil
+}
+
+// mainModulesOf returns the paths of the main module(s) of the packages
+func mainModulesOf(packages []Package) map[string]bool {
+	mainModules := map[string]bool{}
+	for _, p := range packages {
+		if p.Module != nil && p.Module.Main {
+			mainModules[p.Module.Path] = true
+		}
+	}
+	return mainModules
+}
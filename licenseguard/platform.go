@@ -0,0 +1,171 @@
+package licenseguard
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Platform is a GOOS/GOARCH combination to list the dependencies for
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// env returns the environment variables for the platform; empty values are left to the go command's default
+func (p Platform) env() []string {
+	var env []string
+	if p.GOOS != "" {
+		env = append(env, "GOOS="+p.GOOS)
+	}
+	if p.GOARCH != "" {
+		env = append(env, "GOARCH="+p.GOARCH)
+	}
+	return env
+}
+
+// Platforms returns all combinations of the operating systems and architectures; either can be empty for the default
+func Platforms(goos, goarch []string) []Platform {
+	if len(goos) == 0 {
+		goos = []string{""}
+	}
+	if len(goarch) == 0 {
+		goarch = []string{""}
+	}
+	var platforms []Platform
+	for _, os := range goos {
+		for _, arch := range goarch {
+			platforms = append(platforms, Platform{GOOS: os, GOARCH: arch})
+		}
+	}
+	return platforms
+}
+
+// ParsePlatforms parses GOOS/GOARCH pairs, eg. linux/amd64
+func ParsePlatforms(list []string) ([]Platform, error) {
+	var platforms []Platform
+	for _, s := range list {
+		goos, goarch, ok := strings.Cut(s, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, errors.Errorf("invalid platform %q; must be GOOS/GOARCH", s)
+		}
+		platforms = append(platforms, Platform{GOOS: goos, GOARCH: goarch})
+	}
+	return platforms, nil
+}
+
+// goEnv returns the environment for the go command, with extra variables (if any) overriding the current environment
+func goEnv(extra []string) []string {
+	if len(extra) == 0 {
+		return nil // inherit
+	}
+	return append(os.Environ(), extra...)
+}
+
+// getPlatformDependencies lists the dependencies for each of the platforms and returns their union. The files and
+// imports of packages that differ between platforms are merged, so an import that only exists on one platform is
+// still checked. The platforms of each package, and of its imports and dependencies, are kept for the issues.
+func getPlatformDependencies(ctx context.Context, dir string, shards int, platforms []Platform, args ...string) ([]Package, error) {
+	if len(platforms) <= 1 {
+		var env []string
+		if len(platforms) == 1 {
+			env = platforms[0].env()
+		}
+		return getPackageDependencies(ctx, dir, shards, env, args...)
+	}
+
+	byKey := map[string]int{}
+	var packages []Package
+	for _, platform := range platforms {
+		deps, err := getPackageDependencies(ctx, dir, shards, platform.env(), args...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing dependencies for %s", platform)
+		}
+		for _, p := range deps {
+			key := p.ImportPath + "\x00" + p.ForTest // test variants share the import path
+			i, ok := byKey[key]
+			if !ok {
+				i = len(packages)
+				byKey[key] = i
+				p.importPlatforms, p.depPlatforms = map[string][]string{}, map[string][]string{}
+				packages = append(packages, p)
+			}
+			merged := &packages[i]
+			merged.platforms = append(merged.platforms, platform.String())
+			for _, imp := range p.Imports {
+				merged.importPlatforms[imp] = append(merged.importPlatforms[imp], platform.String())
+			}
+			for _, dep := range p.Deps {
+				merged.depPlatforms[dep] = append(merged.depPlatforms[dep], platform.String())
+			}
+			if !ok {
+				continue
+			}
+			merged.GoFiles = mergeUnique(merged.GoFiles, p.GoFiles)
+			merged.CgoFiles = mergeUnique(merged.CgoFiles, p.CgoFiles)
+			merged.CFiles = mergeUnique(merged.CFiles, p.CFiles)
+			merged.CXXFiles = mergeUnique(merged.CXXFiles, p.CXXFiles)
+			merged.HFiles = mergeUnique(merged.HFiles, p.HFiles)
+			merged.Imports = mergeUnique(merged.Imports, p.Imports)
+			merged.Deps = mergeUnique(merged.Deps, p.Deps)
+			merged.DepOnly = merged.DepOnly && p.DepOnly
+		}
+	}
+	return packages, nil
+}
+
+// setPlatforms sets the platforms of the issues of the package, if only some of the platforms that were checked have
+// them: the platforms of the package itself, or those on which it has the imports (or dependencies) of the issue
+func (r *packageResult) setPlatforms(p *Package, o *Options) {
+	if p.platforms == nil {
+		return // a single platform
+	}
+	set := func(issue *Issue) {
+		platforms := p.platforms
+		if len(issue.Imports) > 0 {
+			of := p.importPlatforms
+			if o.Transitive {
+				of = p.depPlatforms
+			}
+			platforms = nil
+			for _, dep := range issue.Imports {
+				for imp, impPlatforms := range of {
+					if normalizeImportPath(imp) == dep.ImportPath {
+						platforms = mergeUnique(platforms, impPlatforms)
+					}
+				}
+			}
+		}
+		if len(platforms) < len(o.Platforms) {
+			issue.Platforms = slices.Sorted(slices.Values(platforms))
+		}
+	}
+	for i := range r.issues {
+		set(&r.issues[i])
+	}
+	for i := range r.warnings {
+		set(&r.warnings[i])
+	}
+}
+
+// mergeUnique appends the strings of b that are not in a
+func mergeUnique(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, s := range a {
+		seen[s] = true
+	}
+	for _, s := range b {
+		if !seen[s] {
+			seen[s] = true
+			a = append(a, s)
+		}
+	}
+	return a
+}
@@ -0,0 +1,62 @@
+package licenseguard
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// findReadmeLicense finds the license in a NOTICE or README file in dir or its parents, up to the module root, for
+// packages without a license file (-scan-readme). Only the "License" section of a README is scanned, if it has one,
+// so that the rest of the README doesn't count against the confidence of the match.
+func findReadmeLicense(dir, moduleDir string, minConfidence int, lc *licenseCorpus) (string, int, licenseMatch, error) {
+	for _, match := range []func(lower string) bool{isNoticeFile, isReadmeFile} {
+		file, distance, err := findFileUp(dir, moduleDir, match)
+		if err != nil {
+			if err != ErrNoLicense {
+				return "", 0, licenseMatch{}, err
+			}
+			continue
+		}
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return "", 0, licenseMatch{}, errors.Wrapf(err, "reading %s", file)
+		}
+		if isReadmeFile(strings.ToLower(filepath.Base(file))) {
+			text = readmeLicenseSection(text)
+		}
+		if lm, err := scanLicense(text, file, minConfidence, lc); err == nil {
+			return file, distance, lm, nil
+		}
+	}
+	return "", 0, licenseMatch{}, ErrNoLicense
+}
+
+func isReadmeFile(lower string) bool {
+	return strings.HasPrefix(lower, "readme")
+}
+
+var (
+	licenseHeadingRegex = regexp.MustCompile(`(?im)^(#+)\s*licen[cs](e|ing)\b.*$`)
+	headingRegex        = regexp.MustCompile(`(?m)^#+`)
+)
+
+// readmeLicenseSection returns the text under the first Markdown "License" heading, up to the next heading of the
+// same or a higher level, or the whole text if there's no such heading
+func readmeLicenseSection(text []byte) []byte {
+	loc := licenseHeadingRegex.FindSubmatchIndex(text)
+	if loc == nil {
+		return text
+	}
+	level := loc[3] - loc[2]
+	section := text[loc[1]:]
+	for _, line := range headingRegex.FindAllIndex(section, -1) {
+		if line[1]-line[0] <= level {
+			return section[:line[0]]
+		}
+	}
+	return section
+}
@@ -0,0 +1,104 @@
+package licenseguard
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// latestModule is the latest version of a module and its license, if it could be determined
+type latestModule struct {
+	Version string
+	License string
+}
+
+// remediate adds suggestions to fix the issues with a denied, incompatible or not allowed license (Options.Remediate):
+// the alternatives of the module from Config.Alternatives, the latest version of the module if its license is
+// acceptable (which needs network access to download it), and removing the import
+func remediate(ctx context.Context, report *ModuleReport, byImportPath map[ImportPath]*Package, o *Options) {
+	latest := map[string]*latestModule{} // by module path; nil if unknown
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		switch issue.Kind {
+		case IssueDeniedImport, IssueIncompatible:
+			for _, imp := range issue.Imports {
+				for _, r := range o.moduleRemediations(ctx, byImportPath[imp.ImportPath], issue.License, latest) {
+					issue.Remediations = appendUnique(issue.Remediations, r)
+				}
+				if !imp.Indirect {
+					issue.Remediations = appendUnique(issue.Remediations, fmt.Sprintf("remove the import of %s from %s", imp.ImportPath, issue.ImportPath))
+				}
+			}
+		case IssueNotAllowed:
+			issue.Remediations = o.moduleRemediations(ctx, byImportPath[issue.ImportPath], "", latest)
+		}
+	}
+}
+
+// moduleRemediations returns the suggestions to replace the module of the package p, which has a license that's
+// denied, not allowed, or incompatible with the license of the importer (if any)
+func (o *Options) moduleRemediations(ctx context.Context, p *Package, importer string, latest map[string]*latestModule) []string {
+	if p == nil || p.Module == nil || p.Module.Main {
+		return nil
+	}
+	m := p.Module
+	var suggestions []string
+	for _, alt := range o.Config.Alternatives[m.Path] {
+		path, version, _ := strings.Cut(alt, "@")
+		suggestions = append(suggestions, fmt.Sprintf("use %s instead of %s: go get %s@%s", path, m.Path, path, cmp.Or(version, "latest")))
+		if version != "" {
+			suggestions = append(suggestions, fmt.Sprintf("or, if %s is a drop-in replacement, add to go.mod: replace %s => %s %s", path, m.Path, path, version))
+		}
+	}
+	if m.Version == "" || m.Replace != nil || o.Offline {
+		return suggestions
+	}
+	l, ok := latest[m.Path]
+	if !ok {
+		l = latestLicense(ctx, m.Path, o)
+		latest[m.Path] = l
+	}
+	if l == nil || l.Version == m.Version || semver.Compare(l.Version, m.Version) < 0 {
+		return suggestions
+	}
+	acceptable := !o.isDenied(l.License, "") && o.Config.isAllowed(l.License, "")
+	if matrix := o.compatibilityMatrix(); matrix != nil && importer != "" {
+		acceptable = acceptable && incompatibility(matrix, importer, l.License) == ""
+	}
+	if acceptable {
+		suggestions = append(suggestions,
+			fmt.Sprintf("upgrade %s to %s, which is %s: go get %s@%s", m.Path, l.Version, l.License, m.Path, l.Version),
+			fmt.Sprintf("or, if %s is not a direct dependency, add to go.mod: exclude %s %s", m.Path, m.Path, m.Version))
+	}
+	return suggestions
+}
+
+// latestLicense downloads the latest version of the module and returns its license, from the license file in the
+// module root; nil if it can't be downloaded or has no license file
+func latestLicense(ctx context.Context, path string, o *Options) *latestModule {
+	downloaded, err := downloadModules(ctx, []string{path + "@latest"})
+	if err != nil {
+		return nil
+	}
+	for _, d := range downloaded {
+		if d.Error != "" || d.Dir == "" {
+			return nil
+		}
+		file, err := findLicenseFile(d.Dir)
+		if err != nil {
+			return nil
+		}
+		match, err := readLicenseFileCached(file, o.MinConfidence, o.corpus)
+		if err != nil {
+			return nil
+		}
+		if o.Log != nil {
+			o.Log.Info("latest version", "module", path, "version", d.Version, "license", match.ID)
+		}
+		return &latestModule{Version: d.Version, License: match.ID}
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package licenseguard
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SchemaVersion is the version of the JSON report format; bump it on breaking changes and update schema.json
+const SchemaVersion = 1
+
+// Schema is the JSON Schema of the report
+//
+//go:embed schema.json
+var Schema []byte
+
+// IssueKind identifies the kind of problem an Issue reports
+type IssueKind string
+
+const (
+	IssueDeniedImport    IssueKind = "denied-import"    // package imports packages with a denied license
+	IssueIncompatible    IssueKind = "incompatible"     // package imports packages with an incompatible license (-compatibility)
+	IssueUnknownLicense  IssueKind = "unknown-license"  // license could not be determined (-fail-unknown)
+	IssueNotAllowed      IssueKind = "not-allowed"      // license is not in the configured allow list
+	IssueWarnedLicense   IssueKind = "warned-license"   // license is in the configured warn list
+	IssueEmptyLicense    IssueKind = "empty-license"    // license file is empty; likely a packaging bug upstream
+	IssueLowConfidence   IssueKind = "low-confidence"   // license file matches are below -min-confidence
+	IssueNotInModCache   IssueKind = "not-in-modcache"  // module has no license in the module cache; check upstream
+	IssueNeedsReview     IssueKind = "needs-review"     // license differs from the one that was reviewed
+	IssueLicenseDistance IssueKind = "license-distance" // license file was found too far up (-max-license-distance)
+	IssueVersionLicense  IssueKind = "version-license"  // major versions of the same module have different licenses
+	IssueLinkname        IssueKind = "linkname"         // package uses //go:linkname (-detect-linkname)
+	IssueLicenseMismatch IssueKind = "license-mismatch" // detected license differs from the package index (-cross-check)
+	IssueLicenseConflict IssueKind = "license-conflict" // source headers and license file disagree
+	IssueBundledLicense  IssueKind = "bundled-license"  // cgo package bundles C code with a denied or not allowed license
+	IssueMissingNotice   IssueKind = "missing-notice"   // NOTICE file that must be redistributed is not in the bundle (-require-notices)
+	IssueScanError       IssueKind = "scan-error"       // files of the package could not be read, so its license is unknown
+	IssueRule            IssueKind = "rule"             // a custom rule of the configuration file applies to the package
+)
+
+// Verdict is the outcome of the policy for the license of a package itself (regardless of its imports)
+type Verdict string
+
+const (
+	VerdictAllowed    Verdict = "allowed"     // license is allowed
+	VerdictDenied     Verdict = "denied"      // license is denied, so packages that aren't can't import it
+	VerdictNotAllowed Verdict = "not-allowed" // license is not in the configured allow list
+	VerdictWarned     Verdict = "warned"      // license is in the configured warn list
+	VerdictUnknown    Verdict = "unknown"     // license could not be determined
+	VerdictIgnored    Verdict = "ignored"     // package matches Options.Ignore, so not checked
+)
+
+// Report is the result of checking one or more modules
+type Report struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Modules       []ModuleReport `json:"modules"`
+	BaselineDiff  *BaselineDiff  `json:"baselineDiff,omitempty"` // changes since the baseline (-baseline)
+}
+
+// ModuleReport is the result of checking a single module
+type ModuleReport struct {
+	Dir              string            `json:"dir"`
+	Workspace        []string          `json:"workspace,omitempty"` // modules of the go.work workspace in Dir, if any
+	Error            string            `json:"error,omitempty"`
+	Packages         []PackageReport   `json:"packages,omitempty"`
+	MainPackages     []PackageReport   `json:"mainPackages,omitempty"` // own packages, with -main-module report-separately
+	Issues           []Issue           `json:"issues,omitempty"`
+	Warnings         []Issue           `json:"warnings,omitempty"`
+	Suppressed       []Issue           `json:"suppressed,omitempty"` // issues that are in the baseline (-new-only)
+	Errors           []Issue           `json:"errors,omitempty"`     // packages that could not be scanned, which are not violations
+	Why              *Why              `json:"why,omitempty"`
+	Explain          *Explanation      `json:"explain,omitempty"`
+	DuplicateModules []DuplicateModule `json:"duplicateModules,omitempty"`
+	LicenseFiles     []LicenseFile     `json:"licenseFiles,omitempty"`
+}
+
+// PackageReport is the license determination for a single (non-standard) package
+type PackageReport struct {
+	ImportPath   ImportPath        `json:"importPath"`
+	Dir          string            `json:"dir"`
+	Module       string            `json:"module,omitempty"`
+	Version      string            `json:"version,omitempty"`
+	ModuleDir    string            `json:"moduleDir,omitempty"`
+	RepoURL      string            `json:"repoURL,omitempty"`
+	License      string            `json:"license,omitempty"`
+	Category     string            `json:"category,omitempty"` // LicenseCategory of each license in License, joined with ", "
+	LicenseURL   string            `json:"licenseURL,omitempty"`
+	LicenseFile  string            `json:"licenseFile,omitempty"` // file the license was read from, if not from source headers
+	Confidence   map[string]int    `json:"confidence,omitempty"`  // percentage of LicenseFile matched by each license ID
+	Expression   string            `json:"expression,omitempty"`  // detected "A OR B" expression, if License is the chosen branch
+	Error        string            `json:"error,omitempty"`
+	Source       LicenseSource     `json:"licenseSource,omitempty"`
+	Review       *Review           `json:"review,omitempty"`
+	Conflict     string            `json:"conflict,omitempty"`        // source headers and license file disagree
+	FileLicenses map[string]string `json:"fileLicenses,omitempty"`    // license of each source file, if they differ
+	Bundled      []BundledLicense  `json:"bundledLicenses,omitempty"` // licenses of bundled C code, with cgo
+	Imports      []ImportPath      `json:"imports,omitempty"`         // with Options.Graph
+	ImportedBy   []ImportPath      `json:"importedBy,omitempty"`      // packages that import this one directly
+	Replace      string            `json:"replace,omitempty"`         // replacement of the module: path@version, or a directory
+	Ignored      bool              `json:"ignored,omitempty"`         // matches Options.Ignore, so not checked
+	Verdict      Verdict           `json:"verdict,omitempty"`
+	Scope        Scope             `json:"scope,omitempty"`      // with -include-tests or -include-tools
+	NoticeFile   string            `json:"noticeFile,omitempty"` // NOTICE file of the package or its module, if any
+	NoticeHash   string            `json:"noticeHash,omitempty"` // SHA-256 of the NOTICE file
+}
+
+// Issue is a problem found with a package
+type Issue struct {
+	Kind       IssueKind    `json:"kind"`
+	ImportPath ImportPath   `json:"importPath"`
+	License    string       `json:"license,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	Distance   int          `json:"distance,omitempty"`
+	Imports    []Dependency `json:"imports,omitempty"`
+	Module     string       `json:"module,omitempty"`  // module of the package, eg. the workspace module with the issue
+	Scope      Scope        `json:"scope,omitempty"`   // scope of the package, with -include-tests or -include-tools
+	Replace    string       `json:"replace,omitempty"` // replacement of the package's module (path@version or directory)
+	Severity   Severity     `json:"severity,omitempty"`
+	Platforms  []string     `json:"platforms,omitempty"` // GOOS/GOARCH that have the issue, if not all that were checked
+	// Remediations are suggestions to fix the issue (-remediate)
+	Remediations []string `json:"remediations,omitempty"`
+}
+
+// Dependency is an imported package and its license
+type Dependency struct {
+	ImportPath ImportPath   `json:"importPath"`
+	License    string       `json:"license"`
+	Chain      []ImportPath `json:"chain,omitempty"`    // shortest import chain from a listed package (-trace)
+	Indirect   bool         `json:"indirect,omitempty"` // not imported directly (-transitive)
+	Rule       string       `json:"rule,omitempty"`     // the compatibility rule that is violated, for incompatible imports
+}
+
+// LicenseFile is a license file and the packages whose license was determined from it
+type LicenseFile struct {
+	Path     string       `json:"path"`
+	License  string       `json:"license"`
+	Packages []ImportPath `json:"packages"`
+}
+
+// DuplicateModule is a module that is used with more than one major version
+type DuplicateModule struct {
+	Path     string          `json:"path"` // module path without major version suffix
+	Versions []ModuleVersion `json:"versions"`
+}
+
+// ModuleVersion is a specific version of a module and its license
+type ModuleVersion struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	License string `json:"license,omitempty"`
+}
+
+// LicensesDiffer reports whether not all versions of the module have the same license
+func (d *DuplicateModule) LicensesDiffer() bool {
+	for _, v := range d.Versions[1:] {
+		if v.License != d.Versions[0].License {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *DuplicateModule) String() string {
+	s := fmt.Sprintf("module %s is used with different licenses:", d.Path)
+	for _, v := range d.Versions {
+		s += fmt.Sprintf(" %s@%s (%s)", v.Path, v.Version, v.License)
+	}
+	return s
+}
+
+func sortedImportPaths(byImportPath map[ImportPath]*Package) []ImportPath {
+	importPaths := make([]ImportPath, 0, len(byImportPath))
+	for importPath := range byImportPath {
+		importPaths = append(importPaths, importPath)
+	}
+	sort.Slice(importPaths, func(i, j int) bool { return importPaths[i] < importPaths[j] })
+	return importPaths
+}
+
+// TextOptions controls the human readable output
+type TextOptions struct {
+	PerModule      bool // print a header per module and a summary
+	GroupByLicense bool // group denied imports by the denied license instead of by importing package
+	Quiet          bool // don't write the warnings
+}
+
+// WriteText writes the issues in human readable form to w and the warnings and errors to errw
+func (r *Report) WriteText(w, errw io.Writer, opts TextOptions) {
+	for _, m := range r.Modules {
+		if opts.PerModule {
+			fmt.Fprintf(w, "== %s ==\n", m.Dir)
+		}
+		if m.Error != "" {
+			fmt.Fprintln(errw, m.Error)
+		}
+		for _, issue := range m.Warnings {
+			if opts.Quiet {
+				break
+			}
+			fmt.Fprintf(errw, "warning: %s\n", issue.text())
+		}
+		for _, issue := range m.Issues {
+			if opts.GroupByLicense && issue.Kind == IssueDeniedImport {
+				continue
+			}
+			var prefix string
+			if len(m.Workspace) > 1 && slices.Contains(m.Workspace, issue.Module) {
+				prefix = "[" + issue.Module + "] "
+			}
+			if issue.Scope != ScopeBuild {
+				prefix += "[" + string(issue.Scope) + "] "
+			}
+			fmt.Fprintln(w, prefix+issue.text())
+		}
+		if opts.GroupByLicense {
+			writeDeniedByLicense(w, m.Issues)
+		}
+		if len(m.Suppressed) > 0 && !opts.Quiet {
+			fmt.Fprintf(errw, "%d known issue(s) in the baseline not reported\n", len(m.Suppressed))
+		}
+		writeUnresolved(w, append(m.MainPackages, m.Packages...))
+		if len(m.Errors) > 0 {
+			fmt.Fprintf(errw, "%d package(s) could not be scanned:\n", len(m.Errors))
+			for _, e := range m.Errors {
+				fmt.Fprintf(errw, "  %s\n", e.text())
+			}
+		}
+	}
+
+	if r.BaselineDiff != nil {
+		r.BaselineDiff.writeText(w)
+	}
+
+	if opts.PerModule {
+		fmt.Fprintln(w, "== summary ==")
+		for _, m := range r.Modules {
+			switch {
+			case m.Error != "":
+				fmt.Fprintf(w, "%s: error: %s\n", m.Dir, m.Error)
+			case len(m.Issues) > 0:
+				fmt.Fprintf(w, "%s: %d issue(s)\n", m.Dir, len(m.Issues))
+			default:
+				fmt.Fprintf(w, "%s: ok\n", m.Dir)
+			}
+		}
+	}
+}
+
+// writeUnresolved lists the packages with an Unknown license and where their license was looked for, so it's clear
+// which packages need an override or a review
+func writeUnresolved(w io.Writer, packages []PackageReport) {
+	var unresolved []PackageReport
+	for _, pr := range packages {
+		if pr.License == UnknownLicense && !pr.Ignored {
+			unresolved = append(unresolved, pr)
+		}
+	}
+	if len(unresolved) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s licensed packages (add an override or a review):\n", UnknownLicense)
+	for _, pr := range unresolved {
+		searched := pr.Dir
+		if pr.ModuleDir != "" && pr.ModuleDir != pr.Dir {
+			searched += " up to " + pr.ModuleDir
+		}
+		if pr.Replace != "" {
+			searched += ", replaced by " + pr.Replace
+		}
+		fmt.Fprintf(w, "  %s (searched %s)\n", pr.ImportPath, searched)
+	}
+}
+
+// writeDeniedByLicense writes the denied imports grouped by the denied license
+func writeDeniedByLicense(w io.Writer, issues []Issue) {
+	byLicense := map[string][]string{}
+	for _, issue := range issues {
+		if issue.Kind != IssueDeniedImport {
+			continue
+		}
+		for _, imp := range issue.Imports {
+			byLicense[imp.License] = append(byLicense[imp.License],
+				fmt.Sprintf("  %s imported by %s (%s)", imp.ImportPath, issue.ImportPath, issue.License))
+		}
+	}
+	licenses := make([]string, 0, len(byLicense))
+	for lic := range byLicense {
+		licenses = append(licenses, lic)
+	}
+	sort.Strings(licenses)
+	for _, lic := range licenses {
+		fmt.Fprintf(w, "%s licensed packages used by:\n", lic)
+		for _, line := range byLicense[lic] {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// String returns the issue as it's written in the text report
+func (i *Issue) String() string {
+	return i.text()
+}
+
+func (i *Issue) text() string {
+	s := i.kindText()
+	if i.Replace != "" {
+		replaced := fmt.Sprintf("module %s is replaced by %s", i.Module, i.Replace)
+		if strings.Contains(s, "\n") {
+			s += "\n  " + replaced // after the imports
+		} else {
+			s += "; " + replaced
+		}
+	}
+	for _, r := range i.Remediations {
+		s += "\n  suggestion: " + r
+	}
+	if len(i.Platforms) > 0 {
+		s = fmt.Sprintf("[%s] %s", strings.Join(i.Platforms, " "), s)
+	}
+	return s
+}
+
+func (i *Issue) kindText() string {
+	switch i.Kind {
+	case IssueDeniedImport:
+		s := fmt.Sprintf("%s licensed package %s using packages:", i.License, i.ImportPath)
+		for _, imp := range i.Imports {
+			verb := "imports"
+			if imp.Indirect {
+				verb = "depends on"
+			}
+			s += fmt.Sprintf("\n  %s %s (%s)", verb, imp.ImportPath, imp.License)
+			if len(imp.Chain) > 0 {
+				s += fmt.Sprintf("\n    via %s", joinImportPaths(imp.Chain, " -> "))
+			}
+		}
+		return s
+	case IssueIncompatible:
+		var lines []string
+		for _, imp := range i.Imports {
+			verb := "imports"
+			if imp.Indirect {
+				verb = "depends on"
+			}
+			line := fmt.Sprintf("%s (%s) %s %s (%s): incompatible", i.ImportPath, i.License, verb, imp.ImportPath, imp.License)
+			if imp.Rule != "" {
+				line += ": " + imp.Rule
+			}
+			if len(imp.Chain) > 0 {
+				line += fmt.Sprintf("\n  via %s", joinImportPaths(imp.Chain, " -> "))
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n")
+	case IssueNotAllowed:
+		return fmt.Sprintf("%s licensed package %s: license not allowed", i.License, i.ImportPath)
+	case IssueWarnedLicense:
+		return fmt.Sprintf("%s licensed package %s: license is on the warn list", i.License, i.ImportPath)
+	case IssueNeedsReview:
+		return fmt.Sprintf("%s licensed package %s needs review: %s", i.License, i.ImportPath, i.Message)
+	case IssueEmptyLicense:
+		return fmt.Sprintf("package %s has an empty license file: %s", i.ImportPath, i.Message)
+	case IssueLowConfidence:
+		return fmt.Sprintf("Unknown (low confidence) license for package %s: %s", i.ImportPath, i.Message)
+	case IssueNotInModCache:
+		return fmt.Sprintf("package %s: %s; check the upstream repository, eg. with -vcs-fallback", i.ImportPath, i.Message)
+	case IssueUnknownLicense:
+		return fmt.Sprintf("undetermined license for package %s: %s", i.ImportPath, i.Message)
+	case IssueLicenseDistance:
+		return fmt.Sprintf("license for package %s found %d directories up", i.ImportPath, i.Distance)
+	case IssueVersionLicense:
+		return i.Message
+	case IssueLicenseConflict:
+		return fmt.Sprintf("%s licensed package %s: %s", i.License, i.ImportPath, i.Message)
+	case IssueLicenseMismatch:
+		return fmt.Sprintf("%s licensed package %s: %s", i.License, i.ImportPath, i.Message)
+	case IssueLinkname:
+		return fmt.Sprintf("package %s uses //go:linkname in %s", i.ImportPath, i.Message)
+	case IssueBundledLicense:
+		return fmt.Sprintf("%s licensed package %s bundles C code: %s", i.License, i.ImportPath, i.Message)
+	case IssueScanError:
+		return fmt.Sprintf("%s (error: %s) licensed package %s", UnknownLicense, i.Message, i.ImportPath)
+	case IssueRule:
+		return fmt.Sprintf("%s licensed package %s: %s", i.License, i.ImportPath, i.Message)
+	case IssueMissingNotice:
+		return fmt.Sprintf("%s licensed package %s: %s is not in the attribution bundle", i.License, i.ImportPath, i.Message)
+	default:
+		return fmt.Sprintf("%s: %s %s", i.Kind, i.ImportPath, i.Message)
+	}
+}
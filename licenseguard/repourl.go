@@ -0,0 +1,58 @@
+package licenseguard
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LoadRepoURLMap reads a JSON file that maps module path prefixes to repository URL prefixes, for vanity import paths
+func LoadRepoURLMap(file string) (map[string]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading repo map %s", file)
+	}
+	repoURLMap := map[string]string{}
+	if err := json.Unmarshal(data, &repoURLMap); err != nil {
+		return nil, errors.Wrapf(err, "parsing repo map %s", file)
+	}
+	return repoURLMap, nil
+}
+
+// repoURL returns the URL of the source repository for the module path, or "" if unknown
+func (o *Options) repoURL(modulePath string) string {
+	var best string
+	for prefix := range o.RepoURLMap {
+		if isPathPrefix(modulePath, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best != "" {
+		return o.RepoURLMap[best] + strings.TrimPrefix(modulePath, best)
+	}
+
+	parts := strings.Split(modulePath, "/")
+	switch parts[0] {
+	case "github.com", "gitlab.com", "bitbucket.org":
+		if len(parts) >= 3 {
+			return "https://" + strings.Join(parts[:3], "/")
+		}
+	case "gopkg.in":
+		// gopkg.in/pkg.v1 is github.com/go-pkg/pkg and gopkg.in/user/pkg.v1 is github.com/user/pkg
+		if len(parts) == 2 {
+			pkg, _, _ := strings.Cut(parts[1], ".")
+			return "https://github.com/go-" + pkg + "/" + pkg
+		}
+		if len(parts) >= 3 {
+			pkg, _, _ := strings.Cut(parts[2], ".")
+			return "https://github.com/" + parts[1] + "/" + pkg
+		}
+	case "golang.org":
+		if len(parts) >= 3 && parts[1] == "x" {
+			return "https://go.googlesource.com/" + parts[2]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package licenseguard
+
+import "testing"
+
+func TestRepoURL(t *testing.T) {
+	o := &Options{RepoURLMap: map[string]string{
+		"go.example.com/lib":    "https://git.example.com/lib",
+		"go.example.com/tools/": "https://git.example.com/tools/",
+	}}
+	tests := []struct {
+		modulePath string
+		want       string
+	}{
+		{"go.example.com/lib", "https://git.example.com/lib"},
+		{"go.example.com/lib/v2", "https://git.example.com/lib/v2"},
+		{"go.example.com/library", ""},
+		{"go.example.com/tools/lint", "https://git.example.com/tools/lint"},
+		{"go.example.com/toolset", ""},
+		{"github.com/foo/bar/v2", "https://github.com/foo/bar"},
+		{"gopkg.in/yaml.v3", "https://github.com/go-yaml/yaml"},
+		{"gopkg.in/src-d/go-git.v4", "https://github.com/src-d/go-git"},
+		{"golang.org/x/mod", "https://go.googlesource.com/mod"},
+	}
+	for _, tt := range tests {
+		if got := o.repoURL(tt.modulePath); got != tt.want {
+			t.Errorf("repoURL(%q) = %q; want %q", tt.modulePath, got, tt.want)
+		}
+	}
+}
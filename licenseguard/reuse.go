@@ -0,0 +1,137 @@
+package licenseguard
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// dep5Stanza is a "Files:" paragraph of a REUSE .reuse/dep5 file (Debian copyright format)
+type dep5Stanza struct {
+	files   []*regexp.Regexp
+	license string
+}
+
+var (
+	dep5Cache   = map[string][]dep5Stanza{} // module dir -> parsed .reuse/dep5 (nil if none)
+	dep5CacheMu sync.Mutex
+)
+
+// readDep5 parses the .reuse/dep5 file in the module root dir, if any
+func readDep5(moduleDir string) ([]dep5Stanza, error) {
+	dep5CacheMu.Lock()
+	stanzas, ok := dep5Cache[moduleDir]
+	dep5CacheMu.Unlock()
+	if ok {
+		return stanzas, nil
+	}
+	f, err := os.Open(filepath.Join(moduleDir, ".reuse", "dep5"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			dep5CacheMu.Lock()
+			dep5Cache[moduleDir] = nil
+			dep5CacheMu.Unlock()
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var current dep5Stanza
+	var field string
+	flush := func() {
+		if len(current.files) > 0 && current.license != "" {
+			stanzas = append(stanzas, current)
+		}
+		current = dep5Stanza{}
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		value := line
+		if line[0] != ' ' && line[0] != '\t' {
+			var ok bool
+			field, value, ok = strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+		} else if field == "License" {
+			continue // continuation lines of License contain the license text
+		}
+		switch field {
+		case "Files":
+			for _, pattern := range strings.Fields(value) {
+				current.files = append(current.files, dep5Pattern(pattern))
+			}
+		case "License":
+			current.license = strings.TrimSpace(value)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", f.Name())
+	}
+	dep5CacheMu.Lock()
+	dep5Cache[moduleDir] = stanzas
+	dep5CacheMu.Unlock()
+	return stanzas, nil
+}
+
+// dep5Pattern converts a dep5 Files pattern, where * and ? also match /, to a regular expression
+func dep5Pattern(pattern string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(strings.TrimPrefix(pattern, "./"))
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// findReuseLicense returns the license the module's .reuse/dep5 declares for all files, or ErrNoLicense if
+// there's no dep5 file or it doesn't cover all files
+func findReuseLicense(moduleDir, dir string, files []string) (string, error) {
+	stanzas, err := readDep5(moduleDir)
+	if err != nil || stanzas == nil {
+		return "", ErrNoLicense
+	}
+	var licenses []string
+	for _, file := range files {
+		rel, err := filepath.Rel(moduleDir, filepath.Join(dir, file))
+		if err != nil {
+			return "", ErrNoLicense
+		}
+		rel = filepath.ToSlash(rel)
+		var license string
+		for _, stanza := range stanzas { // the last matching stanza wins
+			for _, re := range stanza.files {
+				if re.MatchString(rel) {
+					license = stanza.license
+				}
+			}
+		}
+		if license == "" {
+			return "", ErrNoLicense // file not covered
+		}
+		licenses = appendUnique(licenses, license)
+	}
+	if len(licenses) == 0 {
+		return "", ErrNoLicense
+	}
+	return strings.Join(licenses, " AND "), nil
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, l := range list {
+		if l == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
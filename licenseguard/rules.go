@@ -0,0 +1,172 @@
+package licenseguard
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// RuleAction is what a Rule does with the packages it applies to
+type RuleAction string
+
+const (
+	RuleDeny  RuleAction = "deny"  // report the package as an issue
+	RuleWarn  RuleAction = "warn"  // report the package as a warning
+	RuleAllow RuleAction = "allow" // drop the issues and warnings about the license of the package, eg. for a vendor agreement
+)
+
+// Rule is a custom policy rule of the configuration file, for policies that the allow and deny lists can't express,
+// like "no copyleft packages in the server": When is a text/template that is executed with the RuleInput of each
+// package, and the rule applies to the package unless its output is empty or "false". The first rule that applies to
+// a package decides.
+type Rule struct {
+	Name    string     `yaml:"name"`
+	When    string     `yaml:"when"`
+	Action  RuleAction `yaml:"action,omitempty"`  // default deny
+	Message string     `yaml:"message,omitempty"` // text/template of the message of the issue, with the RuleInput; default the name
+
+	when, message *template.Template
+}
+
+// RuleInput is what the templates of a Rule are executed with: the package, and methods about its imports
+type RuleInput struct {
+	PackageReport
+	importOf map[ImportPath][]ImportPath
+}
+
+// Matches reports whether the import path of the package matches the pattern (eg. github.com/mycorp/...)
+func (in RuleInput) Matches(pattern string) bool {
+	return matchesAny([]string{pattern}, in.ImportPath)
+}
+
+// HasLicense reports whether one of the licenses of the package's license expression is the license ID, or has the
+// license category (eg. strong-copyleft)
+func (in RuleInput) HasLicense(idOrCategory string) bool {
+	return slices.ContainsFunc(licenseTerms(in.License), func(lic string) bool { return matchLicense(lic, idOrCategory) })
+}
+
+// ImportedBy reports whether a package that matches the pattern imports the package, directly or not; so it's
+// reachable from (and ships with) eg. the command github.com/mycorp/app/cmd/server
+func (in RuleInput) ImportedBy(pattern string) bool {
+	seen := map[ImportPath]bool{in.ImportPath: true}
+	queue := []ImportPath{in.ImportPath}
+	for len(queue) > 0 {
+		importPath := queue[0]
+		queue = queue[1:]
+		for _, importer := range in.importOf[importPath] {
+			if seen[importer] {
+				continue
+			}
+			if matchesAny([]string{pattern}, importer) {
+				return true
+			}
+			seen[importer] = true
+			queue = append(queue, importer)
+		}
+	}
+	return false
+}
+
+// parseRules checks the rules of the config and parses their templates, with the templateFuncs
+func (c *Config) parseRules() error {
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		switch r.Action {
+		case "":
+			r.Action = RuleDeny
+		case RuleDeny, RuleWarn, RuleAllow:
+		default:
+			return errors.Errorf("%s: unknown action %q; must be deny, warn or allow", r.Name, r.Action)
+		}
+		if strings.TrimSpace(r.When) == "" {
+			return errors.Errorf("%s has no when", r.Name)
+		}
+		var err error
+		if r.when, err = template.New(r.Name).Funcs(templateFuncs).Parse(r.When); err != nil {
+			return errors.Wrapf(err, "parsing the when of %s", r.Name)
+		}
+		if r.message, err = template.New(r.Name).Funcs(templateFuncs).Parse(cmp.Or(r.Message, r.Name)); err != nil {
+			return errors.Wrapf(err, "parsing the message of %s", r.Name)
+		}
+	}
+	return nil
+}
+
+// execute returns the output of the template with the input
+func execute(tmpl *template.Template, in RuleInput) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, in); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// applyRules adds the issues and warnings of the rules of the config that apply to the packages of the report, and
+// drops the ones of the packages that a rule allows
+func (c *Config) applyRules(report *ModuleReport, importOf map[ImportPath][]ImportPath) error {
+	allowed := map[ImportPath]bool{}
+	var issues, warnings []Issue
+	for _, pr := range report.Packages {
+		if pr.Ignored {
+			continue
+		}
+		in := RuleInput{PackageReport: pr, importOf: importOf}
+		for _, r := range c.Rules {
+			out, err := execute(r.when, in)
+			if err != nil {
+				return errors.Wrapf(err, "%s: package %s", r.Name, pr.ImportPath)
+			}
+			if out == "" || out == "false" {
+				continue
+			}
+			message, err := execute(r.message, in)
+			if err != nil {
+				return errors.Wrapf(err, "%s: package %s", r.Name, pr.ImportPath)
+			}
+			issue := Issue{Kind: IssueRule, ImportPath: pr.ImportPath, License: pr.License, Message: message, Module: pr.Module, Scope: pr.Scope}
+			switch r.Action {
+			case RuleDeny:
+				issues = append(issues, issue)
+			case RuleWarn:
+				warnings = append(warnings, issue)
+			case RuleAllow:
+				allowed[pr.ImportPath] = true
+			}
+			break
+		}
+	}
+	if len(allowed) > 0 {
+		report.Issues, report.Warnings = dropAllowed(report.Issues, allowed), dropAllowed(report.Warnings, allowed)
+	}
+	report.Issues = append(report.Issues, issues...)
+	report.Warnings = append(report.Warnings, warnings...)
+	return nil
+}
+
+// dropAllowed drops the issues about the license of the allowed packages: their own issues, and their imports from
+// the issues of the importers, which are dropped when none of their imports are left. The issues about the imports of
+// an allowed package are kept, since those still ship with it.
+func dropAllowed(issues []Issue, allowed map[ImportPath]bool) []Issue {
+	var kept []Issue
+	for _, issue := range issues {
+		if len(issue.Imports) == 0 {
+			if allowed[issue.ImportPath] {
+				continue
+			}
+		} else {
+			issue.Imports = slices.DeleteFunc(slices.Clone(issue.Imports), func(d Dependency) bool { return allowed[d.ImportPath] })
+			if len(issue.Imports) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, issue)
+	}
+	return kept
+}
@@ -0,0 +1,74 @@
+package licenseguard
+
+import "testing"
+
+func TestApplyRules(t *testing.T) {
+	c := Config{Rules: []Rule{
+		{Name: "no copyleft in the server", When: `{{and (.HasLicense "strong-copyleft") (.ImportedBy "example.com/app/cmd/server")}}`,
+			Message: "{{.ImportPath}} is {{.License}}"},
+		{Name: "vendor agreement", When: `{{.Matches "example.com/agpl/..."}}`, Action: RuleAllow},
+		{Name: "eula", When: `{{.HasLicense "LicenseRef-EULA"}}`, Action: RuleWarn},
+	}}
+	if err := c.parseRules(); err != nil {
+		t.Fatal(err)
+	}
+	report := &ModuleReport{
+		Packages: []PackageReport{
+			{ImportPath: "example.com/app/cmd/server", License: "MIT"},
+			{ImportPath: "example.com/app/cmd/tool", License: "MIT"},
+			{ImportPath: "example.com/gpl", License: "GPL-3.0-only"},
+			{ImportPath: "example.com/gpl/tool", License: "GPL-2.0-or-later"},
+			{ImportPath: "example.com/agpl", License: "AGPL-3.0-only"},
+			{ImportPath: "example.com/eula", License: "MIT OR LicenseRef-EULA"},
+		},
+		Issues: []Issue{
+			{Kind: IssueDeniedImport, ImportPath: "example.com/app/cmd/server", Imports: []Dependency{{ImportPath: "example.com/agpl"}}},
+			{Kind: IssueDeniedImport, ImportPath: "example.com/app/cmd/tool", Imports: []Dependency{{ImportPath: "example.com/agpl"}, {ImportPath: "example.com/gpl/tool"}}},
+		},
+	}
+	importOf := map[ImportPath][]ImportPath{
+		"example.com/gpl":      {"example.com/eula"},
+		"example.com/eula":     {"example.com/app/cmd/server"},
+		"example.com/agpl":     {"example.com/app/cmd/server", "example.com/app/cmd/tool"},
+		"example.com/gpl/tool": {"example.com/app/cmd/tool"},
+	}
+	if err := c.applyRules(report, importOf); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 2 {
+		t.Fatalf("got issues %v; want 2", report.Issues)
+	}
+	// the allowed import is dropped from the denied imports, which leaves none for the server
+	if issue := report.Issues[0]; issue.ImportPath != "example.com/app/cmd/tool" || len(issue.Imports) != 1 || issue.Imports[0].ImportPath != "example.com/gpl/tool" {
+		t.Errorf("got %+v; want the denied import of example.com/gpl/tool by the tool", issue)
+	}
+	if issue := report.Issues[1]; issue.Kind != IssueRule || issue.ImportPath != "example.com/gpl" || issue.Message != "example.com/gpl is GPL-3.0-only" {
+		t.Errorf("got %+v; want the rule issue of example.com/gpl", issue)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].ImportPath != "example.com/eula" {
+		t.Errorf("got warnings %v; want the eula of example.com/eula", report.Warnings)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		rule  Rule
+		valid bool
+	}{
+		{Rule{When: `{{.Matches "example.com/..."}}`}, true},
+		{Rule{When: `{{.Matches "example.com/..."}}`, Action: "block"}, false},
+		{Rule{When: " "}, false},
+		{Rule{When: `{{.Matches`}, false},
+		{Rule{When: "true", Message: "{{end}}"}, false},
+	}
+	for _, tt := range tests {
+		c := Config{Rules: []Rule{tt.rule}}
+		if err := c.parseRules(); (err == nil) != tt.valid {
+			t.Errorf("%+v: got %v; want valid %v", tt.rule, err, tt.valid)
+		}
+	}
+	c := Config{Rules: []Rule{{When: "true"}}}
+	if err := c.parseRules(); err != nil || c.Rules[0].Name != "rule 1" || c.Rules[0].Action != RuleDeny {
+		t.Errorf("got %+v, %v; want the default name and action", c.Rules[0], err)
+	}
+}
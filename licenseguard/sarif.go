@@ -0,0 +1,188 @@
+package licenseguard
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SARIF 2.1.0 log for code scanning: a result per issue (and per offending import), located at the import in the
+// importing package's source, or else at the module's line in go.mod.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// findLine returns the first line (1-based) of file that contains s, or 0 if none does
+func findLine(file, s string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.Contains(scanner.Text(), s) {
+			return line
+		}
+	}
+	return 0
+}
+
+// sarifLocationOf returns the location of file (relative to the working directory, if below it) and line
+func sarifLocationOf(file string, line int) []sarifLocation {
+	if wd, err := os.Getwd(); err == nil && isBelow(file, wd) {
+		abs, _ := filepath.Abs(file)
+		file, _ = filepath.Rel(wd, abs)
+	}
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{Uri: filepath.ToSlash(file)}}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	return []sarifLocation{loc}
+}
+
+// isBelow reports whether file is in dir or its subdirectories
+func isBelow(file, dir string) bool {
+	absFile, err1 := filepath.Abs(file)
+	absDir, err2 := filepath.Abs(dir)
+	rel, err3 := filepath.Rel(absDir, absFile)
+	return err1 == nil && err2 == nil && err3 == nil && !strings.HasPrefix(rel, "..")
+}
+
+// findImport returns the non-test source file in dir that imports importPath, and the line of the import
+func findImport(dir string, importPath ImportPath) (string, int) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		if line := findLine(file, strconv.Quote(string(importPath))); line > 0 {
+			return file, line
+		}
+	}
+	return "", 0
+}
+
+// sarifResults returns the results for an issue of a module: one per import for issues about imports, at the
+// import if the importing package is in the module, or else at the importing package's module in go.mod
+func sarifResults(m ModuleReport, issue Issue, level string, packages map[ImportPath]PackageReport) []sarifResult {
+	goMod := filepath.Join(m.Dir, "go.mod")
+	moduleLocation := func(importPath ImportPath) []sarifLocation {
+		line := 0
+		if pr, ok := packages[importPath]; ok && pr.Module != "" {
+			line = findLine(goMod, pr.Module+" ")
+		}
+		return sarifLocationOf(goMod, line)
+	}
+	if len(issue.Imports) == 0 {
+		return []sarifResult{{RuleId: string(issue.Kind), Level: level, Message: sarifMessage{Text: issue.text()}, Locations: moduleLocation(issue.ImportPath)}}
+	}
+	var results []sarifResult
+	for _, imp := range issue.Imports {
+		single := issue
+		single.Imports = []Dependency{imp}
+		result := sarifResult{RuleId: string(issue.Kind), Level: level, Message: sarifMessage{Text: single.text()}}
+		if pr, ok := packages[issue.ImportPath]; ok && !imp.Indirect && isBelow(pr.Dir, m.Dir) {
+			if file, line := findImport(pr.Dir, imp.ImportPath); file != "" {
+				result.Locations = sarifLocationOf(file, line)
+			}
+		}
+		if result.Locations == nil {
+			result.Locations = moduleLocation(issue.ImportPath)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// WriteSarif writes the issues (as errors) and warnings of the report as a SARIF 2.1.0 log, for code scanning
+func WriteSarif(w io.Writer, report Report, toolVersion string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{Name: "golicenseguard", Version: toolVersion,
+			InformationUri: "https://github.com/DefangLabs/GoLicenseGuard"}},
+		Results: []sarifResult{},
+	}
+	kinds := map[IssueKind]bool{}
+	for _, m := range report.Modules {
+		packages := map[ImportPath]PackageReport{}
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			packages[pr.ImportPath] = pr
+		}
+		for _, issue := range m.Issues {
+			kinds[issue.Kind] = true
+			run.Results = append(run.Results, sarifResults(m, issue, "error", packages)...)
+		}
+		for _, issue := range m.Warnings {
+			kinds[issue.Kind] = true
+			run.Results = append(run.Results, sarifResults(m, issue, "warning", packages)...)
+		}
+	}
+	for kind := range kinds {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{Id: string(kind), ShortDescription: sarifMessage{Text: strings.ReplaceAll(string(kind), "-", " ")}})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool { return run.Tool.Driver.Rules[i].Id < run.Tool.Driver.Rules[j].Id })
+	if run.Tool.Driver.Rules == nil {
+		run.Tool.Driver.Rules = []sarifRule{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: "2.1.0", Schema: "https://json.schemastore.org/sarif-2.1.0.json", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,54 @@
+package licenseguard
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ScopePolicy replaces the allow, deny and warn lists of the configuration for the packages of a scope, like the
+// tools that are only run at build time; the lists that are not set are inherited
+type ScopePolicy struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+	Warn  []string `yaml:"warn,omitempty"`
+}
+
+// scopeNames are the names of the scopes in Config.Scopes
+var scopeNames = map[string]Scope{"build": ScopeBuild, "test": ScopeTest, "tool": ScopeTool, "plugin": ScopePlugin}
+
+// validateScopes checks the scope names of the configuration file
+func (c *Config) validateScopes() error {
+	for name := range c.Scopes {
+		if _, ok := scopeNames[name]; !ok {
+			return errors.Errorf("unknown scope %q; must be build, test, tool or plugin", name)
+		}
+	}
+	return nil
+}
+
+// usesScopes reports whether the scope of the packages is needed, for the report or for the policy
+func (o *Options) usesScopes() bool {
+	return o.IncludeTests || o.IncludeTools || len(o.Config.Plugins) > 0
+}
+
+// scopedOptions returns the options to check the packages of each scope with, which have the policy of the scope
+// from Config.Scopes
+func (o *Options) scopedOptions() map[Scope]*Options {
+	scoped := map[Scope]*Options{}
+	for _, scope := range scopeNames {
+		scoped[scope] = o
+	}
+	for name, policy := range o.Config.Scopes {
+		so := *o
+		if policy.Allow != nil {
+			so.Config.Allow = policy.Allow
+		}
+		if policy.Deny != nil {
+			so.Deny = policy.Deny // also replaces -deny
+		}
+		if policy.Warn != nil {
+			so.Config.Warn = policy.Warn
+		}
+		scoped[scopeNames[name]] = &so
+	}
+	return scoped
+}
@@ -0,0 +1,51 @@
+package licenseguard
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Severity is how an issue is reported: as an error (which fails the check), as a warning, or not at all
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // reported in the issues of the report
+	SeverityWarning Severity = "warning" // reported in the warnings of the report
+	SeverityOff     Severity = "off"     // not reported
+)
+
+// validateSeverities checks the severities of the configuration file
+func (c *Config) validateSeverities() error {
+	for kind, severity := range c.Severity {
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityOff:
+		default:
+			return errors.Errorf("invalid severity %q for %s; must be error, warning or off", severity, kind)
+		}
+	}
+	return nil
+}
+
+// applySeverities sets the severity of the issues and warnings, from the Severity of the configuration or else by
+// where they were found, and moves them to the issues or warnings by their severity; issues that are off are dropped
+func (c *Config) applySeverities(issues, warnings []Issue) ([]Issue, []Issue) {
+	var errs, warns []Issue
+	add := func(issue Issue, severity Severity) {
+		if s, ok := c.Severity[issue.Kind]; ok {
+			severity = s
+		}
+		issue.Severity = severity
+		switch severity {
+		case SeverityError:
+			errs = append(errs, issue)
+		case SeverityWarning:
+			warns = append(warns, issue)
+		}
+	}
+	for _, issue := range issues {
+		add(issue, SeverityError)
+	}
+	for _, issue := range warnings {
+		add(issue, SeverityWarning)
+	}
+	return errs, warns
+}
@@ -0,0 +1,60 @@
+package licenseguard
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// getPackageDependenciesSharded lists the packages matching args, splits them into shards and runs go list -deps on
+// the shards concurrently. Packages that are dependencies of more than one shard are only returned once.
+func getPackageDependenciesSharded(ctx context.Context, dir string, shards int, env []string, args ...string) ([]Package, error) {
+	flags, patterns := splitArgs(args)
+
+	// Enumerate the top-level packages; their test packages are listed by go list -deps -test
+	out, err := runGo(ctx, dir, env, append(append([]string{"list"}, withoutTestFlag(flags)...), patterns...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing packages")
+	}
+	roots := strings.Fields(string(out))
+	if len(roots) < shards {
+		shards = len(roots)
+	}
+	if shards <= 1 {
+		return goListDeps(ctx, dir, env, args...)
+	}
+
+	results := make([][]Package, shards)
+	errs := make([]error, shards)
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		var shard []string
+		for j := i; j < len(roots); j += shards {
+			shard = append(shard, roots[j])
+		}
+		wg.Add(1)
+		go func(i int, shard []string) {
+			defer wg.Done()
+			results[i], errs[i] = goListDeps(ctx, dir, env, append(append([]string{}, flags...), shard...)...)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	var packages []Package
+	for i, result := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, p := range result {
+			key := p.ImportPath + "\x00" + p.ForTest // test variants share the import path
+			if !seen[key] {
+				seen[key] = true
+				packages = append(packages, p)
+			}
+		}
+	}
+	return packages, nil
+}
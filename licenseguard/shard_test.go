@@ -0,0 +1,49 @@
+package licenseguard
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestShardedPackages checks that go list in shards lists the same packages, once each, as a single go list
+func TestShardedPackages(t *testing.T) {
+	dir := filepath.Join("testdata", "shards")
+	importPaths := func(shards int) []string {
+		t.Helper()
+		packages, err := getPackageDependencies(context.Background(), dir, shards, nil, "./...")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var importPaths []string
+		for _, p := range packages {
+			importPaths = append(importPaths, p.ImportPath)
+		}
+		sort.Strings(importPaths)
+		return importPaths
+	}
+	want := importPaths(1)
+	if got := importPaths(2); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v with 2 shards; want %v", got, want)
+	}
+}
+
+// BenchmarkScan compares a scan with and without -shards; the license cache is warm after the first scan, so this
+// mostly measures go list. Sharding only pays off for trees with many packages, so it's slower for this small module.
+func BenchmarkScan(b *testing.B) {
+	dir := filepath.Join("testdata", "shards")
+	for _, shards := range []int{1, 2} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			opts := DefaultOptions()
+			opts.Args = []string{"./..."}
+			opts.Shards = shards
+			for i := 0; i < b.N; i++ {
+				if _, err := Scan(dir, opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
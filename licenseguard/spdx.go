@@ -0,0 +1,124 @@
+package licenseguard
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SPDX 2.3 JSON document, limited to the fields golicenseguard can fill in: one SPDX package per non-standard Go
+// package, with the detected license as both the declared and the concluded license.
+
+type spdxDocument struct {
+	SpdxVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SpdxId            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string `json:"name"`
+	SpdxId           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo,omitempty"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	LicenseComments  string `json:"licenseComments,omitempty"`
+	SourceInfo       string `json:"sourceInfo,omitempty"`
+
+	ExternalRefs []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+// spdxIdChars matches the characters that are not allowed in an SPDX identifier
+var spdxIdChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxDownload returns the module proxy URL of the zip of the package's module and its package URL, or "NOASSERTION"
+// and no reference for packages of modules without a version (eg. the main module)
+func spdxDownload(pr PackageReport) (string, []spdxExternalRef) {
+	if pr.Module == "" || pr.Version == "" {
+		return "NOASSERTION", nil
+	}
+	location := "https://proxy.golang.org/" + escapeModulePath(pr.Module) + "/@v/" + escapeModulePath(pr.Version) + ".zip"
+	purl := "pkg:golang/" + pr.Module + "@" + pr.Version
+	if sub := strings.TrimPrefix(string(pr.ImportPath), pr.Module+"/"); sub != string(pr.ImportPath) {
+		purl += "#" + sub
+	}
+	return location, []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: purl}}
+}
+
+// newSpdxDocument converts the report to an SPDX document; packages without a license are NOASSERTION
+func newSpdxDocument(report Report, toolVersion string, created time.Time) spdxDocument {
+	var nonce [8]byte
+	rand.Read(nonce[:])
+	doc := spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SpdxId:            "SPDXRef-DOCUMENT",
+		Name:              "golicenseguard",
+		DocumentNamespace: "https://spdx.org/spdxdocs/golicenseguard-" + hex.EncodeToString(nonce[:]),
+		CreationInfo:      spdxCreationInfo{Created: created.UTC().Format(time.RFC3339), Creators: []string{"Tool: golicenseguard-" + toolVersion}},
+		Packages:          []spdxPackage{},
+		Relationships:     []spdxRelationship{},
+	}
+	seen := map[string]bool{}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			id := "SPDXRef-Package-" + spdxIdChars.ReplaceAllString(string(pr.ImportPath), "-")
+			if seen[id] {
+				continue // same package in more than one module
+			}
+			seen[id] = true
+			lic := pr.License
+			if lic == UnknownLicense {
+				lic = "NOASSERTION"
+			}
+			pkg := spdxPackage{Name: string(pr.ImportPath), SpdxId: id, VersionInfo: pr.Version,
+				LicenseConcluded: lic, LicenseDeclared: lic, SourceInfo: "directory " + pr.Dir}
+			pkg.DownloadLocation, pkg.ExternalRefs = spdxDownload(pr)
+			if pr.LicenseFile != "" {
+				pkg.LicenseComments = "detected in " + pr.LicenseFile
+			} else if pr.Error != "" {
+				pkg.LicenseComments = pr.Error
+			}
+			if pr.Ignored {
+				pkg.LicenseComments = strings.TrimPrefix(pkg.LicenseComments+"; not checked (ignored)", "; ")
+			}
+			doc.Packages = append(doc.Packages, pkg)
+			doc.Relationships = append(doc.Relationships, spdxRelationship{SpdxElementId: doc.SpdxId, RelationshipType: "DESCRIBES", RelatedSpdxElement: id})
+		}
+	}
+	return doc
+}
+
+// WriteSpdx writes the report as an SPDX 2.3 JSON document, created by the given version of golicenseguard
+func WriteSpdx(w io.Writer, report Report, toolVersion string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newSpdxDocument(report, toolVersion, time.Now()))
+}
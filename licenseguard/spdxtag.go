@@ -0,0 +1,34 @@
+package licenseguard
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+const spdxTag = "SPDX-License-Identifier:"
+
+// readSPDXTag returns the expression of the SPDX-License-Identifier tag in the comments at the top of a Go file, ie.
+// before the package clause, or "" if there is none
+func readSPDXTag(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if _, expr, ok := strings.Cut(line, spdxTag); ok {
+			expr = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(expr), "*/"))
+			if expr != "" {
+				return expr, nil
+			}
+		}
+	}
+	return "", scanner.Err()
+}
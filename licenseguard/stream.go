@@ -0,0 +1,153 @@
+package licenseguard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// streamRecord is a single line of the -stream -json output
+type streamRecord struct {
+	Package *PackageReport `json:"package,omitempty"`
+	Issue   *Issue         `json:"issue,omitempty"`
+	Warning *Issue         `json:"warning,omitempty"`
+	Error   *Issue         `json:"error,omitempty"`
+}
+
+// Stream checks the packages of the module in dir while they're decoded from go list. Since go list emits
+// packages after their dependencies, only the licenses of the packages seen so far need to be kept in memory.
+// Checks that need the whole module (like duplicate module versions) are not done. Results are written to w as JSON
+// lines if jsonLines is set, or as text, with the warnings and scan errors written to errw. Returns the number of
+// issues, and an error after the results if some packages could not be scanned. The rules of the configuration and
+// a MainModule other than enforce need the whole module too, and are an error.
+func Stream(dir string, opts Options, w, errw io.Writer, jsonLines bool) (int, error) {
+	if err := opts.useLicenses(); err != nil {
+		return 0, err
+	}
+	args := opts.goListArgs()
+	if len(opts.Platforms) > 1 {
+		return 0, errors.New("streaming supports a single platform only")
+	}
+	if opts.MainModule != "" && opts.MainModule != "enforce" {
+		return 0, errors.Errorf("streaming doesn't support main module mode %s", opts.MainModule)
+	}
+	if len(opts.Config.Rules) > 0 {
+		return 0, errors.New("streaming doesn't support the rules of the configuration file")
+	}
+	var vendored map[string]string
+	if opts.Vendor {
+		var err error
+		if vendored, err = readVendorModules(dir); err != nil {
+			return 0, err
+		}
+	}
+	var env []string
+	if len(opts.Platforms) == 1 {
+		env = opts.Platforms[0].env()
+	}
+	ctx, cancel := withTimeout(withGoCommandLog(context.Background(), &opts), opts.Timeout)
+	defer cancel()
+	args, _, err := workspaceArgs(ctx, dir, args)
+	if err != nil {
+		return 0, errors.Wrapf(timeoutError(err, opts.Timeout), "listing workspace modules of %s", dir)
+	}
+	if opts.IncludeTools {
+		args = withTools(dir, args)
+	}
+	cmd := goCommand(ctx, dir, env, append([]string{"list", "-deps", "-json"}, args...)...)
+	cmd.Stderr = errw
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, errors.Wrapf(err, "listing dependencies of %s", dir)
+	}
+
+	licenses := map[ImportPath]licenseMatch{} // only checked packages
+	depLicense := func(pkg ImportPath) (string, string, bool) {
+		lic, ok := licenses[pkg]
+		return lic.ID, lic.URL, ok
+	}
+
+	var issues, scanErrors int
+	enc := json.NewEncoder(w)
+	decoder := json.NewDecoder(stdout)
+	for {
+		var p Package
+		if err := decoder.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return issues, errors.Wrapf(err, "decoding dependencies of %s", dir)
+		}
+		importPath := normalizeImportPath(p.ImportPath)
+		if opts.isExcluded(importPath) {
+			continue
+		}
+		if vendored != nil {
+			setVendorModuleDirs(map[ImportPath]*Package{importPath: &p}, vendored)
+		}
+		if opts.downloadSources() {
+			deps := []Package{p}
+			if err := downloadMissingSources(ctx, deps, &opts); err != nil {
+				cmd.Process.Kill()
+				cmd.Wait()
+				return issues, errors.Wrap(timeoutError(err, opts.Timeout), "downloading missing modules")
+			}
+			p = deps[0]
+		}
+		res := checkPackage(importPath, &p, depLicense, &opts)
+		res.issues, res.warnings = opts.Config.applySeverities(res.issues, res.warnings)
+		if res.pkg != nil {
+			licenses[importPath] = licenseMatch{ID: res.pkg.License, URL: res.pkg.LicenseURL}
+		}
+		issues += len(res.issues)
+		scanErrors += len(res.errors)
+		if opts.StopOnError && len(res.errors) > 0 {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return issues, errors.Errorf("%s; use -keep-going to report it and continue", res.errors[0].Message)
+		}
+
+		if jsonLines {
+			if res.pkg != nil {
+				enc.Encode(streamRecord{Package: res.pkg})
+			}
+			for i := range res.issues {
+				enc.Encode(streamRecord{Issue: &res.issues[i]})
+			}
+			for i := range res.warnings {
+				enc.Encode(streamRecord{Warning: &res.warnings[i]})
+			}
+			for i := range res.errors {
+				enc.Encode(streamRecord{Error: &res.errors[i]})
+			}
+		} else {
+			for _, issue := range res.warnings {
+				fmt.Fprintf(errw, "warning: %s\n", issue.text())
+			}
+			for _, issue := range res.errors {
+				fmt.Fprintf(errw, "error: %s\n", issue.text())
+			}
+			for _, issue := range res.issues {
+				fmt.Fprintln(w, issue.text())
+			}
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			err = timeoutError(ctx.Err(), opts.Timeout)
+		}
+		return issues, errors.Wrapf(err, "listing dependencies of %s", dir)
+	}
+	if scanErrors > 0 {
+		return issues, errors.Errorf("%d package(s) could not be scanned", scanErrors)
+	}
+	return issues, nil
+}
@@ -0,0 +1,105 @@
+package licenseguard
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+)
+
+// LicenseSummary is a license and the packages that have it
+type LicenseSummary struct {
+	License  string       `json:"license"`
+	Packages []ImportPath `json:"packages"`
+	Modules  []string     `json:"modules"`           // module versions (path@version) of the packages
+	Ignored  []ImportPath `json:"ignored,omitempty"` // the packages that were not checked (-ignore)
+}
+
+// Summarize groups the packages of all modules in the report by license, most used license first
+func Summarize(report Report) []LicenseSummary {
+	byLicense := map[string]*LicenseSummary{}
+	seen := map[ImportPath]bool{}
+	for _, m := range report.Modules {
+		for _, pr := range append(m.MainPackages, m.Packages...) {
+			if seen[pr.ImportPath] {
+				continue // same package in more than one module
+			}
+			seen[pr.ImportPath] = true
+			lic := pr.License
+			ls := byLicense[lic]
+			if ls == nil {
+				ls = &LicenseSummary{License: lic}
+				byLicense[lic] = ls
+			}
+			ls.Packages = append(ls.Packages, pr.ImportPath)
+			if pr.Ignored {
+				ls.Ignored = append(ls.Ignored, pr.ImportPath)
+			}
+		}
+	}
+
+	modules := licenseModules(report)
+	summary := make([]LicenseSummary, 0, len(byLicense))
+	for _, ls := range byLicense {
+		ls.Modules = modules[ls.License]
+		sort.Slice(ls.Packages, func(i, j int) bool { return ls.Packages[i] < ls.Packages[j] })
+		sort.Slice(ls.Ignored, func(i, j int) bool { return ls.Ignored[i] < ls.Ignored[j] })
+		summary = append(summary, *ls)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if len(summary[i].Packages) != len(summary[j].Packages) {
+			return len(summary[i].Packages) > len(summary[j].Packages)
+		}
+		return summary[i].License < summary[j].License
+	})
+	return summary
+}
+
+// WriteSummary writes the number of packages and modules of each license, the totals and (if not 0) how long the scan
+// took, followed by the packages of each license
+func WriteSummary(w io.Writer, summary []LicenseSummary, elapsed time.Duration) {
+	width := 0
+	for _, ls := range summary {
+		width = max(width, len(ls.License))
+	}
+	var packages, unknown int
+	modules := map[string]bool{}
+	for _, ls := range summary {
+		fmt.Fprintf(w, "%-*s  %s / %s\n", width+1, ls.License+":", plural(len(ls.Packages), "package"), plural(len(ls.Modules), "module"))
+		packages += len(ls.Packages)
+		for _, module := range ls.Modules {
+			modules[module] = true
+		}
+		if ls.License == UnknownLicense {
+			unknown = len(ls.Packages)
+		}
+	}
+	fmt.Fprintf(w, "total: %s / %s, %s", plural(packages, "package"), plural(len(modules), "module"), plural(len(summary), "license"))
+	if unknown > 0 {
+		fmt.Fprintf(w, ", %d unknown", unknown)
+	}
+	if elapsed > 0 {
+		fmt.Fprintf(w, "; scanned in %s", elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintln(w)
+
+	for _, ls := range summary {
+		fmt.Fprintf(w, "\n%s (%d)\n", ls.License, len(ls.Packages))
+		for _, importPath := range ls.Packages {
+			if slices.Contains(ls.Ignored, importPath) {
+				fmt.Fprintf(w, "  %s (ignored)\n", importPath)
+			} else {
+				fmt.Fprintf(w, "  %s\n", importPath)
+			}
+		}
+	}
+}
+
+// plural formats a count of things, eg. "1 module" or "2 modules"
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
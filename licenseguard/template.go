@@ -0,0 +1,56 @@
+package licenseguard
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// templateFuncs are the functions available to the templates of ParseTemplateFile, besides the built-in ones
+var templateFuncs = template.FuncMap{
+	"join":      strings.Join,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"replace":   strings.ReplaceAll,
+	"category":  Category,
+	"summarize": Summarize,
+	"modules":   SummarizeModules,
+	"issues":    func(report Report) []string { return reportIssues(report, false) },
+	"warnings":  func(report Report) []string { return reportIssues(report, true) },
+	"csv":       csvField,
+}
+
+// ParseTemplateFile parses a text/template that formats a Report, with the templateFuncs: join, lower, upper,
+// replace, category (of a license), summarize (the Summarize of a report), modules (SummarizeModules), issues and
+// warnings (their texts, like WriteText) and csv (quotes a CSV field if needed)
+func ParseTemplateFile(file string) (*template.Template, error) {
+	text, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading template %s", file)
+	}
+	tmpl, err := template.New(filepath.Base(file)).Funcs(templateFuncs).Parse(string(text))
+	return tmpl, errors.Wrapf(err, "parsing template %s", file)
+}
+
+// WriteTemplate writes the report formatted by the template, which is executed with the Report
+func WriteTemplate(w io.Writer, report Report, tmpl *template.Template) error {
+	return errors.Wrapf(tmpl.Execute(w, report), "executing template %s", tmpl.Name())
+}
+
+// csvField returns the field quoted like encoding/csv does, if it needs to be
+func csvField(field string) string {
+	if field == "" {
+		return "" // encoding/csv quotes a record of one empty field
+	}
+	var sb bytes.Buffer
+	cw := csv.NewWriter(&sb)
+	cw.Write([]string{field})
+	cw.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,5 @@
+package a
+
+import "strings"
+
+func Upper(s string) string { return strings.ToUpper(s) }
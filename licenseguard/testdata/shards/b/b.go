@@ -0,0 +1,5 @@
+package b
+
+import "strings"
+
+func Upper(s string) string { return strings.ToUpper(s) }
@@ -0,0 +1,5 @@
+package c
+
+import "strings"
+
+func Upper(s string) string { return strings.ToUpper(s) }
@@ -0,0 +1,7 @@
+package main
+
+import "example.com/mit/sub"
+
+func main() {
+	sub.Hello()
+}
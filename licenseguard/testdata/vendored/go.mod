@@ -0,0 +1,8 @@
+module example.com/app
+
+go 1.21
+
+require (
+	example.com/bsd v1.0.0
+	example.com/mit v1.2.0
+)
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"example.com/bsd"
+	"example.com/mit"
+)
+
+func main() {
+	bsd.Hello()
+	mit.Hello()
+}
@@ -0,0 +1,206 @@
+package licenseguard
+
+import (
+	"bufio"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Scope is why a package is in the dependencies: to build the listed packages, their tests, their tools, or the
+// plugins that are loaded at run time
+type Scope string
+
+const (
+	ScopeBuild  Scope = ""       // linked into the listed packages
+	ScopeTest   Scope = "test"   // only used by tests (-include-tests)
+	ScopeTool   Scope = "tool"   // only used by tools, from go.mod tool directives or a tools.go file (-include-tools)
+	ScopePlugin Scope = "plugin" // only used by packages built with -buildmode=plugin (Config.Plugins)
+)
+
+// toolsTag is the build tag of the tools.go pattern, a file that imports the tools to track them in go.mod
+const toolsTag = "tools"
+
+// goModTools returns the packages of the tool directives in the go.mod file in dir
+func goModTools(dir string) []string {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var tools []string
+	inBlock := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case inBlock && len(fields) == 1 && fields[0] == ")":
+			inBlock = false
+		case inBlock && len(fields) == 1:
+			tools = append(tools, fields[0])
+		case len(fields) == 2 && fields[0] == "tool" && fields[1] == "(":
+			inBlock = true
+		case len(fields) == 2 && fields[0] == "tool":
+			tools = append(tools, fields[1])
+		}
+	}
+	return tools
+}
+
+// withTools adds the tools build tag to the go list flags, and the "tool" pattern if the go.mod file in dir has tool
+// directives (Go 1.24), keeping the default pattern "." if there was none
+func withTools(dir string, args []string) []string {
+	flags, patterns := splitArgs(args)
+	tagged := false
+	for i, flag := range flags {
+		if tags, ok := strings.CutPrefix(flag, "-tags="); ok {
+			flags[i], tagged = "-tags="+tags+","+toolsTag, true
+		}
+	}
+	if !tagged {
+		flags = append(flags, "-tags="+toolsTag)
+	}
+	if len(goModTools(dir)) > 0 {
+		if len(patterns) == 0 {
+			patterns = append(patterns, ".")
+		}
+		patterns = append(patterns, "tool")
+	}
+	return append(flags, patterns...)
+}
+
+// isToolsFile reports whether the Go file has a build constraint that needs the tools tag
+func isToolsFile(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		return err == nil && !expr.Eval(func(tag string) bool { return tag != toolsTag })
+	}
+	return false
+}
+
+// toolImports returns the imports that only the files of the package that are built with the tools tag have, and
+// whether all of its files are
+func toolImports(p *Package) (map[ImportPath]bool, bool) {
+	var tools, others []string
+	for _, name := range p.sourceFiles() {
+		if file := filepath.Join(p.Dir, name); isToolsFile(file) {
+			tools = append(tools, file)
+		} else {
+			others = append(others, file)
+		}
+	}
+	imports := map[ImportPath]bool{}
+	if len(tools) == 0 {
+		return imports, false
+	}
+	for _, file := range tools {
+		for _, imp := range fileImports(file) {
+			imports[imp] = true
+		}
+	}
+	for _, file := range others {
+		for _, imp := range fileImports(file) {
+			delete(imports, imp)
+		}
+	}
+	return imports, len(others) == 0
+}
+
+// fileImports returns the import paths of a Go file
+func fileImports(file string) []ImportPath {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	var imports []ImportPath
+	for _, imp := range f.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			imports = append(imports, ImportPath(path))
+		}
+	}
+	return imports
+}
+
+// packageScopes returns the scope of each package: packages that the listed (non-test, non-tool, non-plugin) packages
+// import, directly or not, are ScopeBuild; of the others, those that the tools import are ScopeTool, those that the
+// plugins (the listed packages matching the plugin patterns) import are ScopePlugin, and the rest ScopeTest
+func packageScopes(byImportPath map[ImportPath]*Package, toolPaths, plugins []string) map[ImportPath]Scope {
+	scopes := map[ImportPath]Scope{}
+	toolEdges := map[ImportPath]map[ImportPath]bool{} // imports of a package that only its tools.go files have
+	var buildRoots, toolRoots, pluginRoots []ImportPath
+	for _, path := range toolPaths {
+		toolRoots = append(toolRoots, ImportPath(path))
+	}
+	for _, importPath := range sortedImportPaths(byImportPath) {
+		p := byImportPath[importPath]
+		if p.DepOnly || p.ForTest != "" || strings.HasSuffix(p.ImportPath, ".test") || p.Standard {
+			continue
+		}
+		if matchesAny(plugins, importPath) {
+			pluginRoots = append(pluginRoots, importPath)
+			continue
+		}
+		imports, all := toolImports(p)
+		if all || slices.Contains(toolPaths, p.ImportPath) {
+			toolRoots = append(toolRoots, importPath)
+			continue
+		}
+		toolEdges[importPath] = imports
+		for _, imp := range sortedKeys(imports) {
+			toolRoots = append(toolRoots, imp)
+		}
+		buildRoots = append(buildRoots, importPath)
+	}
+
+	// Walk the imports, so the imports of tools.go files can be left out of the build scope
+	var walk func(importPath ImportPath, scope Scope)
+	walk = func(importPath ImportPath, scope Scope) {
+		if _, ok := scopes[importPath]; ok {
+			return
+		}
+		p := byImportPath[importPath]
+		if p == nil {
+			return
+		}
+		scopes[importPath] = scope
+		for _, imp := range p.Imports {
+			if pkg := normalizeImportPath(imp); scope != ScopeBuild || !toolEdges[importPath][pkg] {
+				walk(pkg, scope)
+			}
+		}
+	}
+	for _, importPath := range buildRoots {
+		walk(importPath, ScopeBuild)
+	}
+	for _, importPath := range toolRoots {
+		walk(importPath, ScopeTool)
+	}
+	for _, importPath := range pluginRoots {
+		walk(importPath, ScopePlugin)
+	}
+	for importPath := range byImportPath {
+		if _, ok := scopes[importPath]; !ok {
+			scopes[importPath] = ScopeTest
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,114 @@
+package licenseguard
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Why is the shortest import chain from the listed packages to a package or module (-why)
+type Why struct {
+	Target string       `json:"target"`          // import path or module path that was asked about
+	Chain  []ImportPath `json:"chain,omitempty"` // empty if the listed packages don't use the target
+	// Licenses of the packages in the chain
+	Licenses []string `json:"licenses,omitempty"`
+}
+
+// importChain returns the shortest chain of imports from one of the roots to importPath (inclusive), or nil if
+// importPath is not reachable from any root. It does a breadth-first search from importPath over importOf, which
+// maps each package to the packages that import it.
+func importChain(importOf map[ImportPath][]ImportPath, roots map[ImportPath]bool, importPath ImportPath) []ImportPath {
+	next := map[ImportPath]ImportPath{} // the next package on the way to importPath
+	seen := map[ImportPath]bool{importPath: true}
+	queue := []ImportPath{importPath}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		if roots[pkg] {
+			chain := []ImportPath{pkg}
+			for pkg != importPath {
+				pkg = next[pkg]
+				chain = append(chain, pkg)
+			}
+			return chain
+		}
+		for _, importer := range importOf[pkg] {
+			if !seen[importer] {
+				seen[importer] = true
+				next[importer] = pkg
+				queue = append(queue, importer)
+			}
+		}
+	}
+	return nil
+}
+
+// traceDeniedImports sets the import chain from the root packages to each denied (or incompatible) import (-trace)
+func traceDeniedImports(issues []Issue, importOf map[ImportPath][]ImportPath, roots map[ImportPath]bool) {
+	for i := range issues {
+		if issues[i].Kind != IssueDeniedImport && issues[i].Kind != IssueIncompatible {
+			continue
+		}
+		chain := importChain(importOf, roots, issues[i].ImportPath)
+		if chain == nil {
+			continue
+		}
+		for j := range issues[i].Imports {
+			imp := &issues[i].Imports[j]
+			imp.Chain = append(append([]ImportPath{}, chain...), imp.ImportPath)
+		}
+	}
+}
+
+// findWhy returns the shortest import chain from the roots to the target package, or to any package of the target
+// module, like go mod why
+func findWhy(target string, byImportPath map[ImportPath]*Package, importOf map[ImportPath][]ImportPath, roots map[ImportPath]bool) *Why {
+	why := &Why{Target: target}
+	if _, ok := byImportPath[ImportPath(target)]; ok {
+		why.Chain = importChain(importOf, roots, ImportPath(target))
+	} else {
+		for _, importPath := range sortedImportPaths(byImportPath) {
+			p := byImportPath[importPath]
+			if p.Module == nil || p.Module.Path != target {
+				continue
+			}
+			if chain := importChain(importOf, roots, importPath); chain != nil && (why.Chain == nil || len(chain) < len(why.Chain)) {
+				why.Chain = chain
+			}
+		}
+	}
+	for _, importPath := range why.Chain {
+		why.Licenses = append(why.Licenses, byImportPath[importPath].license)
+	}
+	return why
+}
+
+// writeText writes the chain one package per line with its license, like go mod why
+func (w *Why) writeText(out io.Writer) {
+	fmt.Fprintf(out, "# %s\n", w.Target)
+	if len(w.Chain) == 0 {
+		fmt.Fprintf(out, "(%s is not used by the listed packages)\n", w.Target)
+		return
+	}
+	for i, importPath := range w.Chain {
+		fmt.Fprintf(out, "%s (%s)\n", importPath, w.Licenses[i])
+	}
+}
+
+// WriteWhy writes the -why import chain of each module of the report
+func WriteWhy(w io.Writer, report Report) {
+	for _, m := range report.Modules {
+		if m.Why != nil {
+			m.Why.writeText(w)
+		}
+	}
+}
+
+// joinImportPaths joins the import paths with sep
+func joinImportPaths(importPaths []ImportPath, sep string) string {
+	s := make([]string, len(importPaths))
+	for i, importPath := range importPaths {
+		s[i] = string(importPath)
+	}
+	return strings.Join(s, sep)
+}
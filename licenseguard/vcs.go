@@ -0,0 +1,187 @@
+package licenseguard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+// vcsOrigin is the source of a module version in its repository, like the Origin in the module cache's .info files
+type vcsOrigin struct {
+	VCS    string
+	URL    string
+	Subdir string // directory of the module in the repository; "" for the root
+	Hash   string // commit
+	Ref    string // tag, if there's no Hash
+}
+
+// vcsLicenses are the license files found by vcsLicense for each module@version, or "" if none
+var (
+	vcsLicenses   = map[string]string{}
+	vcsLicensesMu sync.Mutex // held while looking one up, so a repository is only fetched once
+)
+
+// moduleOrigin returns where the module version comes from. The go command records this in the module cache when it
+// downloads the module from its repository, which it does for GOPRIVATE modules. Otherwise the repository is guessed
+// from the module path (see Options.RepoURLMap), with the tag of the version.
+func (o *Options) moduleOrigin(mod *Module) (vcsOrigin, error) {
+	info := filepath.Join(moduleCacheRoot(), "cache", "download", filepath.FromSlash(escapeModulePath(mod.Path)), "@v", mod.Version+".info")
+	if data, err := os.ReadFile(info); err == nil {
+		var v struct{ Origin *vcsOrigin }
+		if err := json.Unmarshal(data, &v); err == nil && v.Origin != nil && v.Origin.URL != "" && (v.Origin.Hash != "" || v.Origin.Ref != "") {
+			return *v.Origin, nil
+		}
+	}
+	url := o.repoURL(mod.Path)
+	if url == "" {
+		return vcsOrigin{}, errors.Errorf("unknown repository for %s", mod.Path)
+	}
+	origin := vcsOrigin{VCS: "git", URL: url}
+	prefix, _, _ := module.SplitPathVersion(mod.Path) // major version suffixes are usually branches, not directories
+	if repoPath := strings.TrimPrefix(url, "https://"); strings.HasPrefix(prefix, repoPath+"/") {
+		origin.Subdir = strings.TrimPrefix(prefix, repoPath+"/")
+	}
+	if module.IsPseudoVersion(mod.Version) {
+		if origin.Hash, _ = module.PseudoVersionRev(mod.Version); origin.Hash != "" {
+			return origin, nil
+		}
+	}
+	origin.Ref = "refs/tags/" + path.Join(origin.Subdir, strings.TrimSuffix(mod.Version, "+incompatible"))
+	return origin, nil
+}
+
+// vcsLicense looks for a license file in the module's repository, in the parent directories of the module (the
+// module itself was already checked), eg. for a module in a monorepo that only has a LICENSE in the repository root.
+// The license file is copied to the user cache directory, which is also where the repositories are fetched to.
+func (p *Package) vcsLicense(o *Options) (string, licenseMatch, error) {
+	m := p.Module
+	if m == nil || m.Replace != nil || m.Version == "" {
+		return "", licenseMatch{}, ErrNoLicense // not from a repository
+	}
+	key := m.Path + "@" + m.Version
+	vcsLicensesMu.Lock()
+	file, ok := vcsLicenses[key]
+	if !ok {
+		var err error
+		if file, err = o.findVCSLicense(m); err != nil && errors.Cause(err) != ErrNoLicense {
+			vcsLicensesMu.Unlock()
+			return "", licenseMatch{}, err // not cached, eg. network errors
+		}
+		vcsLicenses[key] = file
+	}
+	vcsLicensesMu.Unlock()
+	if file == "" {
+		return "", licenseMatch{}, errors.Wrapf(ErrNoLicense, "repository of %s", key)
+	}
+	match, err := readLicenseFileCached(file, o.MinConfidence, o.corpus)
+	return file, match, err
+}
+
+func (o *Options) findVCSLicense(m *Module) (string, error) {
+	cacheDir := licenseCacheFile("vcs")
+	if cacheDir == "" {
+		return "", errors.New("no user cache directory for the repositories")
+	}
+	licenseDir := filepath.Join(cacheDir, "licenses", filepath.FromSlash(escapeModulePath(m.Path))+"@"+m.Version)
+	if file, err := findLicenseFile(licenseDir); err == nil {
+		return file, nil // found by a previous run
+	}
+	origin, err := o.moduleOrigin(m)
+	if err != nil {
+		return "", err
+	}
+	if origin.VCS != "git" {
+		return "", errors.Errorf("%s repository of %s is not supported", origin.VCS, m.Path)
+	}
+	sum := sha256.Sum256([]byte(origin.URL))
+	repo := filepath.Join(cacheDir, hex.EncodeToString(sum[:8]))
+	rev, err := fetchRevision(repo, origin, o)
+	if err != nil {
+		return "", errors.Wrapf(err, "fetching %s", origin.URL)
+	}
+	for dir := origin.Subdir; dir != "" && dir != "."; {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+		args := []string{"ls-tree", "--name-only", rev}
+		if dir != "" {
+			args = append(args, dir+"/")
+		}
+		out, err := git(repo, args...)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			if !isLicenseFile(strings.ToLower(path.Base(name))) {
+				continue
+			}
+			text, err := git(repo, "show", rev+":"+name)
+			if err != nil {
+				return "", err
+			}
+			if err := os.MkdirAll(licenseDir, 0755); err != nil {
+				return "", err
+			}
+			file := filepath.Join(licenseDir, path.Base(name))
+			if o.Log != nil {
+				o.Log.Info("license file in repository", "module", m.Path, "file", name, "repo", origin.URL, "rev", rev)
+			}
+			return file, os.WriteFile(file, text, 0644)
+		}
+	}
+	return "", ErrNoLicense
+}
+
+// fetchRevision makes sure the commit of the origin is in the bare repository (a clone without file contents,
+// which are fetched when needed), and returns its hash
+func fetchRevision(repo string, origin vcsOrigin, o *Options) (string, error) {
+	if _, err := os.Stat(repo); err != nil {
+		if o.Log != nil {
+			o.Log.Info("cloning repository", "repo", origin.URL, "dir", repo)
+		}
+		if _, err := git("", "clone", "--bare", "--filter=blob:none", "--quiet", origin.URL, repo); err != nil {
+			os.RemoveAll(repo)
+			return "", err
+		}
+	}
+	rev := origin.Hash
+	if rev == "" {
+		rev = origin.Ref
+	}
+	if out, err := git(repo, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err == nil {
+		return strings.TrimSpace(string(out)), nil
+	}
+	if _, err := git(repo, "fetch", "--filter=blob:none", "--quiet", "--tags", "origin", "+refs/heads/*:refs/heads/*"); err != nil {
+		return "", err
+	}
+	out, err := git(repo, "rev-parse", "--verify", rev+"^{commit}")
+	return strings.TrimSpace(string(out)), err
+}
+
+// git runs git in dir, returning its output, or its stderr in the error
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Errorf("git %s: %v: %s", args[0], err, msg)
+		}
+		return nil, errors.Wrapf(err, "git %s", args[0])
+	}
+	return out, nil
+}
@@ -0,0 +1,47 @@
+package licenseguard
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// readVendorModules returns the root directory in dir/vendor of each module in dir/vendor/modules.txt, by module path.
+// Replaced modules are vendored by their original path.
+func readVendorModules(dir string) (map[string]string, error) {
+	vendorDir, err := filepath.Abs(filepath.Join(dir, "vendor"))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(vendorDir, "modules.txt"))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading vendored modules; run go mod vendor")
+	}
+	defer f.Close()
+	modules := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// "# path version [=> replacement [version]]"; "## explicit" and package lines are skipped
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "#" {
+			continue
+		}
+		modules[fields[1]] = filepath.Join(vendorDir, filepath.FromSlash(fields[1]))
+	}
+	return modules, scanner.Err()
+}
+
+// setVendorModuleDirs sets the directory of the modules of vendored packages, which go list leaves empty
+func setVendorModuleDirs(byImportPath map[ImportPath]*Package, modules map[string]string) {
+	for _, p := range byImportPath {
+		if p.Module == nil || p.Module.Dir != "" || p.Module.Main {
+			continue
+		}
+		if dir, ok := modules[p.Module.Path]; ok {
+			p.Module.Dir = dir
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package licenseguard
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// TestScanVendor scans a module with -mod=vendor, for which go list doesn't report the directories of the modules
+func TestScanVendor(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "vendored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Vendor = true
+	opts.Args = []string{"./..."}
+	report, err := Scan(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendor := filepath.Join(dir, "vendor", "example.com")
+	want := map[ImportPath]struct{ license, moduleDir string }{
+		"example.com/app":          {"MIT", dir},
+		"example.com/app/cmd/tool": {"MIT", dir},
+		"example.com/bsd":          {"BSD-3-Clause", filepath.Join(vendor, "bsd")},
+		"example.com/mit":          {"MIT", filepath.Join(vendor, "mit")},
+		"example.com/mit/sub":      {"MIT", filepath.Join(vendor, "mit")},
+	}
+	got := map[ImportPath]bool{}
+	for _, pr := range report.Packages {
+		w, ok := want[pr.ImportPath]
+		if !ok {
+			continue
+		}
+		got[pr.ImportPath] = true
+		if pr.License != w.license || pr.ModuleDir != w.moduleDir {
+			t.Errorf("%s: got %s in %s; want %s in %s", pr.ImportPath, pr.License, pr.ModuleDir, w.license, w.moduleDir)
+		}
+	}
+	for importPath := range want {
+		if !got[importPath] {
+			t.Errorf("%s is not in the report", importPath)
+		}
+	}
+	if len(report.Issues) > 0 {
+		t.Errorf("got issues %v; want none", report.Issues)
+	}
+}
+
+// TestStreamVendor streams the packages of a module with -mod=vendor, which have the licenses of their vendored modules
+func TestStreamVendor(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "vendored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Vendor = true
+	opts.Args = []string{"./..."}
+	var out, errOut bytes.Buffer
+	issues, err := Stream(dir, opts, &out, &errOut, true)
+	if err != nil || issues > 0 {
+		t.Fatalf("got %d issues, %v; want none (%s)", issues, err, errOut.String())
+	}
+	got := map[ImportPath]*PackageReport{}
+	for decoder := json.NewDecoder(&out); ; {
+		var record streamRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if record.Package != nil {
+			got[record.Package.ImportPath] = record.Package
+		}
+	}
+	vendor := filepath.Join(dir, "vendor", "example.com")
+	want := map[ImportPath]struct{ license, moduleDir string }{
+		"example.com/bsd":     {"BSD-3-Clause", filepath.Join(vendor, "bsd")},
+		"example.com/mit/sub": {"MIT", filepath.Join(vendor, "mit")},
+	}
+	for importPath, w := range want {
+		if pr := got[importPath]; pr == nil || pr.License != w.license || pr.ModuleDir != w.moduleDir {
+			t.Errorf("%s: got %+v; want %s in %s", importPath, pr, w.license, w.moduleDir)
+		}
+	}
+}
+
+func TestReadVendorModules(t *testing.T) {
+	dir := filepath.Join("testdata", "vendored")
+	modules, err := readVendorModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendor, _ := filepath.Abs(filepath.Join(dir, "vendor"))
+	want := map[string]string{
+		"example.com/bsd": filepath.Join(vendor, "example.com", "bsd"),
+		"example.com/mit": filepath.Join(vendor, "example.com", "mit"),
+	}
+	if len(modules) != len(want) {
+		t.Errorf("got %v; want %v", modules, want)
+	}
+	for path, moduleDir := range want {
+		if modules[path] != moduleDir {
+			t.Errorf("%s: got %q; want %q", path, modules[path], moduleDir)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package licenseguard
+
+import (
+	"context"
+	"strings"
+)
+
+// workspaceArgs replaces the default patterns (none, "." or "./...") with the modules of the workspace when dir is in
+// a go.work workspace, because go list does not match packages in the workspace modules from the workspace root. All
+// modules are listed in a single go list run, so their dependencies are deduplicated and cross-module imports are kept.
+// It also returns the module paths of the workspace, if any.
+func workspaceArgs(ctx context.Context, dir string, args []string) ([]string, []string, error) {
+	flags, patterns := splitArgs(args)
+	if len(patterns) > 1 || len(patterns) == 1 && patterns[0] != "." && patterns[0] != "./..." {
+		return args, nil, nil
+	}
+	out, err := runGo(ctx, dir, nil, "env", "GOWORK")
+	if err != nil {
+		return nil, nil, err
+	}
+	if gowork := strings.TrimSpace(string(out)); gowork == "" || gowork == "off" {
+		return args, nil, nil
+	}
+	out, err = runGo(ctx, dir, nil, append(append([]string{"list", "-m"}, withoutTestFlag(flags)...), "-f", "{{.Path}}")...)
+	if err != nil {
+		return nil, nil, err
+	}
+	modules := strings.Fields(string(out))
+	for _, path := range modules {
+		flags = append(flags, path+"/...")
+	}
+	return flags, modules, nil
+}
@@ -1,232 +1,798 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 
-	"github.com/google/licensecheck"
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
 	"github.com/pkg/errors"
 )
 
-var ErrNoLicense = fmt.Errorf("no license found")
+// exit codes
+const (
+	exitViolation = 1 // a policy violation was found
+	exitError     = 2 // the scan failed, or some packages could not be scanned (or a license could not be resolved, with -strict)
+)
 
-func findLicenseFile(dir string) (string, error) {
-	f, err := os.Open(dir)
-	if err != nil {
-		return "", err
+var (
+	failUnknown      = flag.Bool("fail-unknown", false, "fail when a package's license cannot be determined")
+	failOn           = flag.String("fail-on", "deny", "fail on issues (\"deny\"), also on undetermined licenses (\"unknown\"), or also on warnings (\"warn\")")
+	listJSON         = flag.String("list-json", "", "file with the output of go list -deps -json to check, instead of running go list; - for stdin")
+	baselineFile     = flag.String("baseline", "", "JSON file with the license of each package from an earlier run, to report the changes since")
+	writeBaseline    = flag.Bool("write-baseline", false, "write the license of each package to the -baseline file, instead of comparing with it")
+	failOnChange     = flag.Bool("fail-on-change", false, "exit with code 1 when the licenses changed since the -baseline, or between the -diff reports")
+	diffMode         = flag.Bool("diff", false, "compare two JSON reports (or baselines) given as the arguments, instead of checking packages")
+	newOnly          = flag.Bool("new-only", false, "only report (and fail on) the issues that are not in the -baseline")
+	reposFile        = flag.String("repos", "", "file with a list of module directories to check, one per line")
+	maxDistance      = flag.Int("max-license-distance", -1, "warn when the license file was found more than N directories above the package; -1 to disable")
+	failDistance     = flag.Bool("fail-license-distance", false, "fail instead of warn when -max-license-distance is exceeded")
+	jsonOutput       = flag.Bool("json", false, "write the report as JSON (same as -format json)")
+	format           = flag.String("format", "text", "report format: text, json, spdx (SPDX 2.3 JSON), cyclonedx (CycloneDX 1.5 JSON), sarif, csv, markdown, html or github (GitHub Actions annotations)")
+	templateFile     = flag.String("template", "", "write the report with this Go text/template `file` instead of a -format; it's executed with the report")
+	sbom             = flag.String("sbom", "", "write an SBOM instead of the report: spdx or cyclonedx (same as -format)")
+	githubComment    = flag.Bool("github-comment", false, "post the violations as a comment on the pull request of the GitHub Actions workflow run, or update the earlier comment (needs GITHUB_TOKEN)")
+	printSchema      = flag.Bool("print-schema", false, "print the JSON schema of the -json report and exit")
+	preview          = flag.String("preview", "", "check the license of module@version and its dependencies without adding it to go.mod")
+	changedOnly      = flag.Bool("changed-only", false, "only check the modules of go.sum that were added or changed since -changed-since, without go list (for git hooks)")
+	changedSince     = flag.String("changed-since", "HEAD", "git revision to compare go.sum with for -changed-only, eg. @{upstream} in a pre-push hook")
+	binaryFile       = flag.String("binary", "", "check the licenses of the modules compiled into a Go binary, from its build information")
+	imageRef         = flag.String("image", "", "check the Go binaries in a container image (eg. ghcr.io/foo/bar:tag), or an image saved with docker save")
+	detectLinkname   = flag.Bool("detect-linkname", false, "warn about packages that use //go:linkname")
+	overridesFile    = flag.String("overrides", "", "YAML or JSON file mapping import path prefixes to license IDs")
+	repoMapFile      = flag.String("repo-map", "", "JSON file mapping module path prefixes to repository URL prefixes")
+	watch            = flag.Bool("watch", false, "check again whenever go.mod, go.sum, go.work or the configuration file changes, until interrupted")
+	serveAddr        = flag.String("serve", "", "serve an HTTP API on this address (eg. :8080) to check modules or go.sum files")
+	crossCheckIndex  = flag.Bool("cross-check", false, "fail when a detected license disagrees with the one recorded by deps.dev (requires network)")
+	indexFallback    = flag.Bool("index-fallback", false, "look up the license of modules whose license can't be detected on deps.dev (requires network)")
+	vcsFallback      = flag.Bool("vcs-fallback", false, "look for the license files of modules without one in the parent directories of the module in its repository, eg. a monorepo's root (requires git and network)")
+	remediate        = flag.Bool("remediate", false, "suggest alternatives, upgrades and go.mod directives to fix each violation (downloads the latest version of the modules)")
+	offline          = flag.Bool("offline", false, "don't access the network: disables -index-fallback, also when enabled in the config file")
+	configFile       = flag.String("config", licenseguard.DefaultConfigFile, "configuration file; .golicenseguard.yaml or .golicenseguard.json is used if the default does not exist")
+	initConfig       = flag.Bool("init", false, "write a starter configuration file based on the current dependencies and exit")
+	force            = flag.Bool("force", false, "overwrite an existing configuration file with -init")
+	conflictPolicy   = flag.String("license-conflict", "prefer-header", "how to resolve source headers that disagree with the license file: prefer-header, prefer-file, most-restrictive or error")
+	groupBy          = flag.String("group-by", "package", "group denied imports by \"package\" or by \"license\"")
+	version          = flag.Bool("version", false, "print the version of golicenseguard and of the go command and exit")
+	verbose          = flag.Bool("v", false, "log how the license of each package was determined to stderr")
+	veryVerbose      = flag.Bool("vv", false, "like -v, and also log the raw licensecheck matches of each license file")
+	debugLog         = flag.Bool("debug", false, "like -vv, and also log the go commands that are run")
+	quiet            = flag.Bool("q", false, "don't write warnings to stderr, only the report and errors")
+	shards           = flag.Int("shards", 1, "split the packages into N shards and run go list on them concurrently")
+	jobs             = flag.Int("j", 0, "number of packages whose license is found concurrently (default GOMAXPROCS)")
+	includeTests     = flag.Bool("include-tests", false, "also check the test packages and their (test-only) dependencies")
+	includeTools     = flag.Bool("include-tools", false, "also check the tools of go.mod tool directives and tools.go files (with the tools build tag)")
+	vendor           = flag.Bool("vendor", false, "list the packages in the vendor directory (-mod=vendor) and find their licenses there, without network access")
+	noDownload       = flag.Bool("no-download", false, "don't download the modules of packages whose directory is missing (eg. after a partial go clean -modcache, or with -list-json)")
+	scanReadme       = flag.Bool("scan-readme", false, "look for the license in NOTICE and README files of packages without a license file")
+	keepGoing        = flag.Bool("keep-going", true, "report the packages whose files can't be read as Unknown (error: ...) and check the others, exiting with code 2; -keep-going=false stops at the first one")
+	strict           = flag.Bool("strict", false, "fail with exit code 2 when the license of a package could not be determined")
+	compatibility    = flag.Bool("compatibility", false, "report imports whose license is incompatible with the importing package's, using the default compatibility matrix (or the one in the config file)")
+	moduleLicense    = flag.Bool("module-license", false, "check all dependencies against the license of the module, from the license file at its root, with the compatibility matrix")
+	transitive       = flag.Bool("transitive", false, "check all (indirect) dependencies of each package for denied licenses, not only its imports")
+	timeout          = flag.Duration("timeout", 2*time.Minute, "maximum time to wait for go list; 0 for no timeout")
+	pullTimeout      = flag.Duration("pull-timeout", 10*time.Minute, "maximum time to pull the -image from its registry; 0 for no timeout")
+	graph            = flag.String("graph", "", "write the import graph instead of the report: dot (Graphviz), with the packages colored by license category")
+	why              = flag.String("why", "", "print the shortest import chain from the listed packages to this package or module, instead of the issues")
+	explainPkg       = flag.String("explain", "", "print how the license and verdict of this package were determined: the license file or headers and their matches, caches, policy rules, import chains and issues")
+	trace            = flag.Bool("trace", false, "show the shortest import chain from the listed packages to each denied import")
+	minConfidence    = flag.Int("min-confidence", 50, "ignore license file matches that cover less than this percentage of the (unmatched) text")
+	stream           = flag.Bool("stream", false, "report packages while go list is running, using less memory (JSON lines with -json)")
+	ortFile          = flag.String("ort", "", "write an OSS Review Toolkit (ORT) analyzer result to this file")
+	metricsFile      = flag.String("metrics-file", "", "write the metrics of the scan (duration, cache hits, packages, findings by severity and license) to this file, in the Prometheus text format")
+	attestFile       = flag.String("attest", "", "write the report as an in-toto attestation to this file")
+	attestImage      = flag.String("attest-image", "", "attach the report as a signed attestation to this container image, with cosign")
+	checklistFile    = flag.String("checklist", "", "write a Markdown checklist of license obligations to this file")
+	copyLicensesDir  = flag.String("copy-licenses", "", "copy the license and NOTICE files of all dependencies into this directory, as DIR/module@version/LICENSE, with a manifest.json")
+	requireNotices   = flag.String("require-notices", "", "fail if the NOTICE file of an Apache-2.0 (or other NOTICE requiring) dependency is not in this attribution bundle (a -notices file or -copy-licenses directory)")
+	noticesFile      = flag.String("notices", "", "write the license texts and NOTICE files of all dependencies to this file")
+	generateFile     = flag.String("generate", "", "write a Go source `file` (eg. licenses_gen.go) with a map of the dependency modules to their licenses, to show them at runtime")
+	generatePackage  = flag.String("generate-package", "", "package name of the -generate file (default the package of the other Go files in its directory)")
+	generateTexts    = flag.Bool("generate-texts", false, "also write the license and NOTICE texts of the modules to the -generate file")
+	mainModule       = flag.String("main-module", "enforce", "how to treat the main module's own packages: enforce, report-separately or skip")
+	noCache          = flag.Bool("no-cache", false, "scan all license files and headers, instead of using the licenses found by previous runs")
+	incremental      = flag.String("incremental", "", "keep the detected licenses in this state `file`, and reuse them for the modules whose go.sum hash is unchanged")
+	mode             = flag.String("mode", "package", "report \"package\" licenses, or aggregate them per \"module\" version")
+	summary          = flag.Bool("summary", false, "print the number of packages and the packages for each license, instead of the issues")
+	tags             = flag.String("tags", "", "comma-separated build tags to list the dependencies with")
+	acceptExceptions listFlag
+	denyLicenses     listFlag
+	allowLicenses    listFlag
+	preferLicenses   listFlag
+	goos             listFlag
+	goarch           listFlag
+	platforms        listFlag
+	attestSubjects   listFlag
+	ignorePatterns   listFlag
+	excludePatterns  listFlag
+	entrypoints      listFlag
+)
+
+// opts are the options for licenseguard.Scan, from the command line flags and the configuration file
+var opts = licenseguard.DefaultOptions()
+
+func init() {
+	flag.Var(&denyLicenses, "deny", "comma-separated SPDX license IDs (or substrings of IDs) or categories that non-denied code must not import (default AGPL)")
+	flag.Var(&allowLicenses, "allow", "comma-separated SPDX license IDs that are allowed; packages with any other (or an unknown) license fail")
+	flag.Var(&preferLicenses, "prefer", "comma-separated licenses (or categories) to choose for dual licensed packages, most preferred first")
+	flag.Var(&goos, "goos", "comma-separated operating systems to list the dependencies for; all combinations with -goarch are checked")
+	flag.Var(&goarch, "goarch", "comma-separated architectures to list the dependencies for")
+	flag.Var(&attestSubjects, "attest-subject", "comma-separated files that the -attest attestation is about (default the -binary, or go.mod and go.sum)")
+	flag.Var(&platforms, "platforms", "comma-separated GOOS/GOARCH pairs (eg. linux/amd64,darwin/arm64) to list the dependencies for")
+	flag.Var(&acceptExceptions, "accept-exceptions", "comma-separated list of SPDX license exceptions (eg. Classpath-exception-2.0) that make a license acceptable")
+	flag.Var(&ignorePatterns, "ignore", "comma-separated import path patterns (eg. github.com/mycorp/legacy/...) of packages that are not checked, nor reported as denied imports")
+	flag.Var(&entrypoints, "entrypoints", "comma-separated package patterns (eg. ./cmd/...) of the main packages that ship; only the packages they import, directly or not, are checked")
+	flag.Var(&excludePatterns, "exclude", "comma-separated import path patterns (eg. github.com/mycorp/**) of packages that are left out of the scan and the report")
+}
+
+// started is when the scan started, for the duration in the -summary
+var started = time.Now()
+
+// logger writes the warnings to stderr, and with -v, -vv and -debug the log of the scan; see newLogger
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// newLogger returns the logger for the verbosity flags: -q only logs errors, -v also how the license of each package
+// was determined (info), and -vv and -debug also the licensecheck matches and go commands (debug)
+func newLogger() *slog.Logger {
+	level := slog.LevelWarn
+	switch {
+	case *quiet:
+		level = slog.LevelError
+	case *veryVerbose:
+		level = slog.LevelDebug
+	case *verbose:
+		level = slog.LevelInfo
 	}
-	entries, err := f.ReadDir(-1)
-	f.Close()
-	if err != nil {
-		return "", err
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level, ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{} // the lines are read as they're written
+		}
+		return a
+	}}))
+}
+
+// listFlag is a flag.Value for a list of strings, which can be given comma-separated or by repeating the flag
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
 	}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	return nil
+}
+
+func isFlagSet(name string) bool {
+	var set bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-		lower := strings.ToLower(entry.Name())
-		switch lower {
-		case "copying", "copying.md", "copying.markdown", "copying.txt", // this list is from https://pkg.go.dev/license-policy
-			"licence", "licence.md", "licence.markdown", "licence.txt",
-			"license", "license.md", "license.markdown", "license.txt",
-			"license-2.0.txt", "licence-2.0.txt", "license-apache", "licence-apache",
-			"license-apache-2.0.txt", "licence-apache-2.0.txt", "license-mit", "licence-mit",
-			"license.mit", "licence.mit", "license.code", "licence.code",
-			"license.docs", "licence.docs", "license.rst", "licence.rst",
-			"mit-license", "mit-licence", "mit-license.md", "mit-licence.md",
-			"mit-license.markdown", "mit-licence.markdown", "mit-license.txt", "mit-licence.txt",
-			"mit_license", "mit_licence", "unlicense", "unlicence",
-			"license_apache2": // used by grafana/loki
-			return filepath.Join(dir, entry.Name()), nil
-		}
-	}
-	return "", ErrNoLicense
+	})
+	return set
 }
 
-func findLicenseFileUp(dir string) (string, error) {
-	for {
-		lic, err := findLicenseFile(dir)
-		if err != nil {
-			if err != ErrNoLicense {
-				return "", err
-			}
-		} else {
-			return lic, nil
+// readRepoList returns the module directories listed in file, one per line. Relative paths are relative to the file.
+func readRepoList(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading repo list %s", file)
+	}
+	var dirs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-		dir = filepath.Dir(dir)
-		if !strings.Contains(dir, "@") {
-			break
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(file), line)
 		}
+		dirs = append(dirs, line)
 	}
-	return "", ErrNoLicense
+	return dirs, nil
 }
 
-type ImportPath string
+// checkModule checks the dependencies of the module in dir (or the given go list args)
+func checkModule(dir string, args ...string) (*licenseguard.ModuleReport, error) {
+	o := opts
+	o.Args = args
+	report, err := licenseguard.Scan(dir, o)
+	saveLicenseCache()
+	return report, err
+}
 
-func normalizeImportPath(importPath string) ImportPath {
-	return ImportPath(strings.TrimPrefix(importPath, "vendor/"))
+// saveLicenseCache saves the licenses that were found for the next run, unless -no-cache
+func saveLicenseCache() {
+	if *noCache {
+		return
+	}
+	if err := licenseguard.SaveLicenseCache(); err != nil {
+		logger.Warn("saving the license cache", "err", err)
+	}
 }
 
-var licenseIdCache = map[string]string{} // file -> license ID mapping
+func main() {
+	flag.Parse()
 
-func (p *Package) findLicense() (string, error) {
-	if p.license != "" {
-		return p.license, nil
+	if *printSchema {
+		os.Stdout.Write(licenseguard.Schema)
+		return
 	}
-	if p.Standard {
-		return "standard", nil
+
+	goVersion, err := checkGoVersion()
+	if *version {
+		fmt.Printf("golicenseguard %s (%s)\n", toolVersion(), goVersion)
 	}
-	if p.ForTest != "" {
-		return "test", nil
+	if err != nil && !*version && (*diffMode || *listJSON != "" && *noDownload) {
+		// the go command isn't run: -diff only reads reports, and -list-json reads the go list output (eg. of a
+		// hermetic build) instead of running go list, and only runs go mod download for missing directories
+		err = nil
 	}
-
-	// Check whether (all) the source files contain a license header
-	licenseId, err := findLicenseHeaders(p.Dir, p.GoFiles)
 	if err != nil {
-		// Look for a LICENSE* file in the package directory (or parents) instead
-		licenseFile, err := findLicenseFileUp(p.Dir)
-		if err != nil {
-			return "", errors.Wrapf(err, "finding license file for %s", p.ImportPath)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	if *version {
+		return
+	}
+	if *debugLog {
+		*veryVerbose = true
+	}
+	if *veryVerbose {
+		*verbose = true
+	}
+	if *quiet && *verbose {
+		fmt.Fprintln(os.Stderr, "-q can't be used with -v, -vv or -debug")
+		os.Exit(exitError)
+	}
+	logger = newLogger()
+	if goVersion != "" {
+		logger.Info("go command", "version", goVersion)
+	}
+
+	if *groupBy != "package" && *groupBy != "license" {
+		fmt.Fprintf(os.Stderr, "invalid -group-by %q\n", *groupBy)
+		os.Exit(exitError)
+	}
+	switch *failOn {
+	case "deny":
+	case "unknown", "warn":
+		*failUnknown = true
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -fail-on %q\n", *failOn)
+		os.Exit(exitError)
+	}
+	if *graph != "" && *graph != "dot" {
+		fmt.Fprintf(os.Stderr, "invalid -graph %q\n", *graph)
+		os.Exit(exitError)
+	}
+	if *mode != "package" && *mode != "module" {
+		fmt.Fprintf(os.Stderr, "invalid -mode %q\n", *mode)
+		os.Exit(exitError)
+	}
+
+	if *listJSON != "" && (*stream || *watch || *serveAddr != "" || *reposFile != "" || *preview != "" || *binaryFile != "" || *imageRef != "") {
+		fmt.Fprintln(os.Stderr, "-list-json can't be used with -stream, -watch, -serve, -repos, -preview, -binary or -image")
+		os.Exit(exitError)
+	}
+
+	if isFlagSet("changed-since") {
+		*changedOnly = true
+	}
+	if *changedOnly && (*listJSON != "" || *stream || *watch || *serveAddr != "" || *reposFile != "" || *preview != "" || *binaryFile != "" || *imageRef != "" || len(entrypoints) > 0) {
+		fmt.Fprintln(os.Stderr, "-changed-only can't be used with -list-json, -stream, -watch, -serve, -repos, -preview, -binary, -image or -entrypoints")
+		os.Exit(exitError)
+	}
+
+	if *metricsFile != "" && (*stream || *watch || *serveAddr != "") {
+		fmt.Fprintln(os.Stderr, "-metrics-file can't be used with -stream, -watch or -serve (which has GET /metrics)")
+		os.Exit(exitError)
+	}
+
+	if len(entrypoints) > 0 && (*stream || *preview != "" || *binaryFile != "" || *imageRef != "") {
+		fmt.Fprintln(os.Stderr, "-entrypoints can't be used with -stream, -preview, -binary or -image")
+		os.Exit(exitError)
+	}
+
+	if *templateFile != "" {
+		if isFlagSet("format") || *jsonOutput {
+			fmt.Fprintln(os.Stderr, "-template can't be used with -format or -json")
+			os.Exit(exitError)
+		}
+		var err error
+		if reportTemplate, err = licenseguard.ParseTemplateFile(*templateFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
 		}
+	}
 
-		licenseId = licenseIdCache[licenseFile]
-		if licenseId == "" { // not in cache
-			licenseId, err = ReadLicenseFile(licenseFile)
-			if err != nil {
-				return "", err
-			}
-			licenseIdCache[licenseFile] = licenseId
+	if *offline && (*crossCheckIndex || *indexFallback) {
+		fmt.Fprintln(os.Stderr, "-offline can't be used with -cross-check or -index-fallback")
+		os.Exit(exitError)
+	}
+
+	if (*writeBaseline || *newOnly) && *baselineFile == "" {
+		fmt.Fprintln(os.Stderr, "-write-baseline and -new-only need a -baseline file")
+		os.Exit(exitError)
+	}
+
+	if len(allowLicenses) > 0 && len(denyLicenses) > 0 {
+		fmt.Fprintln(os.Stderr, "-allow and -deny are mutually exclusive")
+		os.Exit(exitError)
+	}
+
+	switch *conflictPolicy {
+	case "prefer-header", "prefer-file", "most-restrictive", "error":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -license-conflict %q\n", *conflictPolicy)
+		os.Exit(exitError)
+	}
+
+	if *jsonOutput {
+		*format = "json"
+	}
+	if *sbom != "" {
+		if *sbom != "spdx" && *sbom != "cyclonedx" {
+			fmt.Fprintf(os.Stderr, "invalid -sbom %q\n", *sbom)
+			os.Exit(exitError)
+		}
+		*format = *sbom
+	}
+	switch *format {
+	case "text", "json", "spdx", "cyclonedx", "sarif", "csv", "markdown", "html", "github":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -format %q\n", *format)
+		os.Exit(exitError)
+	}
+	if *mode == "module" && (*format != "text" && *format != "json" || *stream) {
+		fmt.Fprintln(os.Stderr, "-mode module can only be used with -format text or json, and not with -stream")
+		os.Exit(exitError)
+	}
+	if *stream && (*mainModule != "enforce" || *failOn == "warn") {
+		fmt.Fprintln(os.Stderr, "-main-module report-separately or skip and -fail-on warn can't be used with -stream")
+		os.Exit(exitError)
+	}
+	if *graph != "" && *format != "text" && *format != "json" {
+		// the graph is the text output, or the imports of the packages of the JSON report (or a -template)
+		fmt.Fprintf(os.Stderr, "-graph can only be used with -format text or json, not %s\n", *format)
+		os.Exit(exitError)
+	}
+
+	if *diffMode {
+		if flag.NArg() != 2 || *format != "text" && *format != "json" {
+			fmt.Fprintln(os.Stderr, "-diff needs the old and the new report, and can only be used with -format text or json")
+			os.Exit(exitError)
+		}
+		changed, err := diffReports(flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
 		}
+		if changed && *failOnChange {
+			os.Exit(exitViolation)
+		}
+		return
 	}
 
-	p.license = licenseId
-	return licenseId, nil
-}
+	switch *mainModule {
+	case "enforce", "report-separately", "skip":
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -main-module %q\n", *mainModule)
+		os.Exit(exitError)
+	}
 
-func findLicenseHeaders(dir string, files []string) (string, error) {
-	licenseIds := map[string]int{}
-	for _, file := range files {
-		license, err := ReadLicenseFile(filepath.Join(dir, file))
+	opts.Deny = denyLicenses
+	opts.Prefer = preferLicenses
+	opts.AcceptExceptions = acceptExceptions
+	opts.Ignore = ignorePatterns
+	opts.Exclude = excludePatterns
+	opts.Entrypoints = entrypoints
+	opts.FailUnknown = *failUnknown
+	opts.MaxDistance = *maxDistance
+	opts.FailDistance = *failDistance
+	opts.Transitive = *transitive
+	opts.Compatibility = *compatibility
+	opts.ModuleLicense = *moduleLicense
+	opts.ScanReadme = *scanReadme
+	opts.DetectLinkname = *detectLinkname
+	opts.ConflictPolicy = *conflictPolicy
+	opts.MainModule = *mainModule
+	opts.MinConfidence = *minConfidence
+	opts.Incremental = *incremental
+	opts.VCSFallback = *vcsFallback
+	opts.Remediate = *remediate
+	opts.StopOnError = !*keepGoing
+	opts.RequireNotices = *requireNotices
+	opts.NoDownload = *noDownload
+	opts.Shards = *shards
+	opts.Jobs = *jobs
+	opts.Timeout = *timeout
+	opts.PullTimeout = *pullTimeout
+	opts.Trace = *trace
+	opts.Why = *why
+	opts.Explain = *explainPkg
+	opts.Graph = *graph != ""
+	opts.Tags = *tags
+	if *listJSON == "-" {
+		opts.PackageList = os.Stdin
+	} else if *listJSON != "" {
+		f, err := os.Open(*listJSON)
 		if err != nil {
-			return "", err // bail on first error (eg. file without license)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
 		}
-		licenseIds[license]++
+		defer f.Close()
+		opts.PackageList = f
 	}
-	for licenseId, _ := range licenseIds {
-		return licenseId, nil // TODO: handle multiple licenses
+	opts.IncludeTests = *includeTests
+	opts.IncludeTools = *includeTools
+	opts.Vendor = *vendor
+	if len(platforms) > 0 {
+		if len(goos) > 0 || len(goarch) > 0 {
+			fmt.Fprintln(os.Stderr, "-platforms can't be used with -goos or -goarch")
+			os.Exit(exitError)
+		}
+		if opts.Platforms, err = licenseguard.ParsePlatforms(platforms); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	} else if len(goos) > 0 || len(goarch) > 0 {
+		opts.Platforms = licenseguard.Platforms(goos, goarch)
+	}
+	opts.CrossCheck = *crossCheckIndex
+	opts.IndexFallback = *indexFallback
+	opts.Offline = *offline
+	if *verbose {
+		opts.Log = logger
+		opts.LogMatches = *veryVerbose
+		opts.LogCommands = *debugLog
 	}
-	return "", ErrNoLicense
-}
 
-func ReadLicenseFile(licenseFile string) (string, error) {
-	license, err := os.ReadFile(licenseFile)
-	if err != nil {
-		return "", errors.Wrapf(err, "reading license file %s", licenseFile)
+	if !*noCache {
+		if err := licenseguard.LoadLicenseCache(); err != nil {
+			logger.Warn("loading the license cache", "err", err)
+		}
 	}
-	cov := licensecheck.Scan(license)
-	if len(cov.Match) == 0 {
-		return "", errors.Wrapf(ErrNoLicense, "scanning license file %s", licenseFile)
+
+	if *overridesFile != "" {
+		if opts.Overrides, err = licenseguard.LoadOverrides(*overridesFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
 	}
-	return cov.Match[0].ID, nil // TODO: handle multiple licenses
-}
 
-// Package represents a Go package. This (partial) definition is copied from the `go help list` command.
-type Package struct {
-	Dir        string   // directory containing package sources
-	ImportPath string   // import path of package in dir
-	Imports    []string // import paths used by this package
-	ForTest    string   // package is only for use in named test
-	Deps       []string // all (recursively) imported dependencies
-	Standard   bool     // is this package part of the standard Go library?
-	GoFiles    []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
-
-	license string
-}
+	if *initConfig {
+		mr, err := checkModule(".", flag.Args()...)
+		if err == nil {
+			err = licenseguard.WriteStarterConfig(*configFile, mr, *force)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("wrote %s\n", *configFile)
+		return
+	}
 
-// getPackageDependencies returns a list of dependencies for the current module, including their paths and directories
-func getPackageDependencies() ([]Package, error) {
-	out, err := exec.Command("go", "list", "-deps", "-json").Output()
-	if err != nil {
-		return nil, err
+	if !isFlagSet("config") {
+		*configFile = licenseguard.FindConfigFile()
+	}
+	if opts.Config, err = licenseguard.LoadConfig(*configFile, isFlagSet("config")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	if len(allowLicenses) > 0 {
+		opts.Config.Allow = allowLicenses // overrides the configuration file
 	}
 
-	decoder := json.NewDecoder(strings.NewReader(string(out)))
-	var packages []Package
+	if *repoMapFile != "" {
+		if opts.RepoURLMap, err = licenseguard.LoadRepoURLMap(*repoMapFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
 
-	for {
-		var mod Package
-		if err := decoder.Decode(&mod); err != nil {
-			break
+	if *serveAddr != "" {
+		if err := serve(*serveAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
 		}
-		packages = append(packages, mod)
+		return
 	}
 
-	return packages, nil
-}
+	if *watch {
+		watchModule(".", flag.Args()...)
+		return
+	}
 
-func main() {
-	// Step 1: Get the list of dependencies
-	deps, err := getPackageDependencies()
-	if err != nil {
-		panic(err)
+	if *stream {
+		o := opts
+		o.Args = flag.Args()
+		issues, err := licenseguard.Stream(".", o, os.Stdout, os.Stderr, *format == "json")
+		saveLicenseCache()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		if issues > 0 {
+			os.Exit(exitViolation)
+		}
+		return
 	}
 
-	// Step 2: Iterate over dependencies and read LICENSE file
-	byImportPath := map[ImportPath]*Package{}
-	depOf := map[ImportPath][]ImportPath{}
-	importOf := map[ImportPath][]ImportPath{}
+	if *imageRef != "" {
+		reports, err := licenseguard.ScanImage(*imageRef, opts)
+		saveLicenseCache()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			writeMetricsFile(licenseguard.Report{}, err)
+			os.Exit(exitError)
+		}
+		if len(reports) == 0 {
+			fmt.Fprintf(os.Stderr, "no Go binaries found in %s\n", *imageRef)
+		}
+		report := licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion, Modules: reports}
+		writeReport(report, true)
+		writeMetricsFile(report, nil)
+		if err := attest(report, nil); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		for i := range reports {
+			if len(reports[i].Errors) > 0 {
+				os.Exit(exitError)
+			}
+		}
+		for i := range reports {
+			if violates(&reports[i]) {
+				os.Exit(exitViolation)
+			}
+		}
+		return
+	}
 
-	for _, dep := range deps {
-		// make a copy of dep on the heap
-		pdep := new(Package)
-		*pdep = dep
-		importPath := normalizeImportPath(dep.ImportPath)
-		byImportPath[importPath] = pdep
-		if dep.ForTest != "" || dep.Standard {
-			continue
+	if *preview != "" || *binaryFile != "" || *changedOnly {
+		var mr *licenseguard.ModuleReport
+		var err error
+		if *binaryFile != "" {
+			mr, err = licenseguard.ScanBinary(*binaryFile, opts)
+			saveLicenseCache()
+		} else if *changedOnly {
+			mr, err = licenseguard.ScanChanged(".", *changedSince, opts)
+			saveLicenseCache()
+		} else {
+			mr, err = previewModule(context.Background(), *preview)
 		}
-		for _, d := range dep.Deps {
-			pkg := normalizeImportPath(d)
-			depOf[pkg] = append(depOf[pkg], importPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			writeMetricsFile(licenseguard.Report{}, err)
+			os.Exit(exitError)
+		}
+		report := licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion, Modules: []licenseguard.ModuleReport{*mr}}
+		writeReport(report, false)
+		writeMetricsFile(report, nil)
+		if err := attest(report, nil); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		if len(mr.Errors) > 0 {
+			os.Exit(exitError)
 		}
-		for _, d := range dep.Imports {
-			pkg := normalizeImportPath(d)
-			importOf[pkg] = append(importOf[pkg], importPath)
+		if violates(mr) {
+			os.Exit(exitViolation)
 		}
+		return
 	}
 
-	// Step 3: Check for license compatibility
-	var issues int
-	for importPath, p := range byImportPath {
-		lic, _ := p.findLicense()
-		if strings.Contains(lic, "AGPL") {
-			continue
+	dirs := []string{"."}
+	if *reposFile != "" {
+		var err error
+		dirs, err = readRepoList(*reposFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
 		}
+	}
 
-		var found bool
-		for _, imp := range p.Imports {
-			pkg := normalizeImportPath(imp)
-			p := byImportPath[pkg]
-			if p == nil || p.Standard || p.ForTest != "" {
-				continue
-			}
-			depLic, _ := p.findLicense()
-			if strings.Contains(depLic, "AGPL") {
-				if !found {
-					fmt.Printf("%s licensed package %s using packages:\n", lic, importPath)
-					issues++
+	report := licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion}
+	var failed, broken bool
+	for _, dir := range dirs {
+		mr, err := checkModule(dir, flag.Args()...)
+		if err != nil {
+			mr = &licenseguard.ModuleReport{Dir: dir, Error: err.Error()}
+		}
+		if mr.Error != "" || len(mr.Errors) > 0 {
+			broken = true
+		}
+		if violates(mr) {
+			failed = true
+		}
+		if *strict {
+			for _, pr := range mr.Packages {
+				if pr.Error != "" {
+					fmt.Fprintf(os.Stderr, "unresolved license of package %s: %s\n", pr.ImportPath, pr.Error)
+					broken = true
 				}
-				found = true
-				fmt.Printf("  imports %s (%s)\n", pkg, depLic)
 			}
 		}
+		report.Modules = append(report.Modules, *mr)
 	}
 
-	if issues > 0 {
-		os.Exit(1)
+	if *baselineFile != "" && !*writeBaseline {
+		baseline, err := licenseguard.LoadBaseline(*baselineFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		if *newOnly {
+			baseline.Suppress(&report)
+			failed = false
+			for i := range report.Modules {
+				failed = failed || violates(&report.Modules[i])
+			}
+		}
+		report.BaselineDiff = licenseguard.DiffBaseline(baseline, report)
+		if *failOnChange && !report.BaselineDiff.Empty() {
+			failed = true
+		}
+	}
+
+	writeReport(report, *reposFile != "")
+	writeMetricsFile(report, nil)
+
+	if *githubComment {
+		if err := postGitHubComment(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *writeBaseline {
+		if err := licenseguard.WriteBaseline(*baselineFile, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *checklistFile != "" {
+		if err := licenseguard.WriteChecklistFile(*checklistFile, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *noticesFile != "" {
+		if err := licenseguard.WriteNoticesFile(*noticesFile, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *generateFile != "" {
+		if err := licenseguard.GenerateGoFile(*generateFile, report, licenseguard.GenerateOptions{Package: *generatePackage, Texts: *generateTexts}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if *copyLicensesDir != "" {
+		if err := licenseguard.CopyLicenses(*copyLicensesDir, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if err := attest(report, dirs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	if *ortFile != "" {
+		if err := licenseguard.WriteOrtFile(*ortFile, report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+	}
+
+	if broken {
+		os.Exit(exitError)
+	}
+	if failed {
+		os.Exit(exitViolation)
+	}
+}
+
+// violates reports whether the module has issues, or warnings with -fail-on warn
+func violates(mr *licenseguard.ModuleReport) bool {
+	return len(mr.Issues) > 0 || *failOn == "warn" && len(mr.Warnings) > 0
+}
+
+// writeMetricsFile writes the metrics of the scan to the -metrics-file, if any; err is the error the scan failed with
+func writeMetricsFile(report licenseguard.Report, err error) {
+	if *metricsFile == "" {
+		return
+	}
+	metrics.Observe(report, time.Since(started), err)
+	if err := metrics.WriteMetricsFile(*metricsFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+}
+
+// reportTemplate is the parsed -template, if any
+var reportTemplate *template.Template
+
+func writeReport(report licenseguard.Report, perModule bool) {
+	if reportTemplate != nil {
+		if err := licenseguard.WriteTemplate(os.Stdout, report, reportTemplate); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitError)
+		}
+		return
+	}
+	var err error
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if *mode == "module" {
+			err = enc.Encode(licenseguard.SummarizeModules(report))
+		} else if *summary {
+			err = enc.Encode(licenseguard.Summarize(report))
+		} else {
+			err = enc.Encode(report)
+		}
+	case "spdx":
+		err = licenseguard.WriteSpdx(os.Stdout, report, toolVersion())
+	case "cyclonedx":
+		err = licenseguard.WriteCycloneDX(os.Stdout, report, toolVersion())
+	case "sarif":
+		err = licenseguard.WriteSarif(os.Stdout, report, toolVersion())
+	case "csv":
+		err = licenseguard.WriteCsv(os.Stdout, report)
+	case "markdown":
+		err = licenseguard.WriteMarkdown(os.Stdout, report)
+	case "html":
+		err = licenseguard.WriteHTML(os.Stdout, report)
+	case "github":
+		err = licenseguard.WriteGitHub(os.Stdout, report)
+	default:
+		if *graph != "" {
+			licenseguard.WriteDot(os.Stdout, report)
+			break
+		}
+		if *why != "" {
+			licenseguard.WriteWhy(os.Stdout, report)
+			break
+		}
+		if *explainPkg != "" {
+			licenseguard.WriteExplain(os.Stdout, report)
+			break
+		}
+		if *mode == "module" {
+			licenseguard.WriteModuleSummary(os.Stdout, licenseguard.SummarizeModules(report))
+			break
+		}
+		if *summary {
+			licenseguard.WriteSummary(os.Stdout, licenseguard.Summarize(report), time.Since(started))
+			break
+		}
+		report.WriteText(os.Stdout, os.Stderr, licenseguard.TextOptions{PerModule: perModule, GroupByLicense: *groupBy == "license", Quiet: *quiet})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
 	}
 }
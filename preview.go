@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+	"github.com/pkg/errors"
+	"golang.org/x/mod/module"
+)
+
+// resolveModule downloads the module of the query (eg. module@latest) into the module cache with go mod download, and
+// returns the module version it resolved to
+func resolveModule(ctx context.Context, dir, moduleQuery string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", moduleQuery)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	var m struct {
+		Path    string
+		Version string
+		Error   string
+	}
+	if decErr := json.Unmarshal(out, &m); decErr != nil {
+		if err == nil {
+			err = decErr
+		}
+		return "", errors.Wrapf(err, "downloading %s", moduleQuery)
+	}
+	if m.Error != "" {
+		return "", errors.New(m.Error) // already mentions the module query
+	}
+	return m.Path + "@" + m.Version, nil
+}
+
+// previewModule downloads module@version (the latest version if there's none) into a temporary module and checks all
+// of its packages and their dependencies, until ctx is done. The current module's go.mod is left untouched.
+func previewModule(ctx context.Context, moduleQuery string) (*licenseguard.ModuleReport, error) {
+	modulePath, version, _ := strings.Cut(moduleQuery, "@")
+	// the query is an argument of the go command, which must not take it for a flag
+	if err := module.CheckPath(modulePath); err != nil || strings.HasPrefix(version, "-") {
+		return nil, errors.Errorf("invalid module %q; must be path@version", moduleQuery)
+	}
+	if !strings.Contains(moduleQuery, "@") {
+		moduleQuery += "@latest"
+	}
+	fetchCtx := ctx // fetching the module has the -timeout, like scanning it
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	timeoutError := func(err error) error {
+		if fetchCtx.Err() == context.DeadlineExceeded {
+			return errors.Errorf("fetching %s did not finish within the timeout of %s", moduleQuery, opts.Timeout)
+		}
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "golicenseguard-preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module golicenseguard-preview\n"), 0644); err != nil {
+		return nil, err
+	}
+	resolved, err := resolveModule(fetchCtx, tmp, moduleQuery)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	cmd := exec.CommandContext(fetchCtx, "go", "get", resolved)
+	cmd.Dir = tmp
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, timeoutError(errors.Wrapf(err, "fetching %s: %s", resolved, strings.TrimSpace(string(out))))
+	}
+
+	o := opts
+	o.Args = []string{"-mod=mod", modulePath + "/..."}
+	report, err := licenseguard.ScanContext(ctx, tmp, o)
+	saveLicenseCache()
+	if err != nil {
+		return nil, err
+	}
+	report.Dir = resolved
+	return report, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+	"github.com/pkg/errors"
+)
+
+// maxRequestSize is the largest request body the server accepts, which is plenty for a go.sum
+const maxRequestSize = 16 << 20
+
+// checkRequest is the JSON body of a POST /check request
+type checkRequest struct {
+	Module string `json:"module"` // module@version to check, like -preview
+	GoSum  string `json:"goSum"`  // contents of a go.sum file to check the modules of
+}
+
+// scanMu serializes the scans, which share the license caches and the go command's module cache
+var scanMu sync.Mutex
+
+// metrics are the metrics of the scans of the server, or of the command for -metrics-file
+var metrics = licenseguard.NewMetrics()
+
+// serve answers POST /check requests with the JSON report of a module@version (like -preview), or of the modules
+// of a go.sum file (sent as JSON, or as the request body itself), until the server fails. The license cache is shared
+// by all requests. GET /metrics has the metrics of the scans so far, in the Prometheus text format.
+func serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /check", handleCheck)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.Write(w)
+	})
+	fmt.Fprintf(os.Stderr, "listening on %s\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func handleCheck(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	var req checkRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(err, "parsing the request"))
+			return
+		}
+	} else {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.Wrap(err, "reading the request"))
+			return
+		}
+		req.GoSum = string(data)
+	}
+	if (req.Module == "") == (req.GoSum == "") {
+		writeError(w, http.StatusBadRequest, errors.New("the request needs either a module or a go.sum"))
+		return
+	}
+
+	scanMu.Lock()
+	start := time.Now()
+	var mr *licenseguard.ModuleReport
+	var err error
+	if req.Module != "" {
+		mr, err = previewModule(r.Context(), req.Module)
+	} else {
+		mr, err = licenseguard.ScanGoSum(strings.NewReader(req.GoSum), opts)
+		saveLicenseCache()
+	}
+	scanMu.Unlock()
+	var report licenseguard.Report
+	if mr != nil {
+		report = licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion, Modules: []licenseguard.ModuleReport{*mr}}
+	}
+	metrics.Observe(report, time.Since(start), err)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
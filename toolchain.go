@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// minGoMinor is the oldest supported go1.x toolchain; go list -deps -json has had the module info fields since
+// go1.11, but workspaces need go1.18
+const minGoMinor = 18
+
+var goVersionRegex = regexp.MustCompile(`^go1\.([0-9]+)`)
+
+// checkGoVersion returns the version of the installed go command, or an error if it's too old
+func checkGoVersion() (string, error) {
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "running go env GOVERSION (go1.%d or later is required)", minGoMinor)
+	}
+	version := strings.TrimSpace(string(out))
+	if strings.HasPrefix(version, "devel") {
+		return version, nil
+	}
+	m := goVersionRegex.FindStringSubmatch(version)
+	if m == nil {
+		return version, errors.Errorf("unrecognized go version %q", version)
+	}
+	if minor, _ := strconv.Atoi(m[1]); minor < minGoMinor {
+		return version, errors.Errorf("%s is too old; go1.%d or later is required", version, minGoMinor)
+	}
+	return version, nil
+}
+
+// toolVersion returns the version of golicenseguard itself, if known
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/DefangLabs/GoLicenseGuard/licenseguard"
+)
+
+const watchInterval = time.Second
+
+// watchModule checks the module in dir (or the given go list args) whenever its go.mod, go.sum, go.work or
+// configuration file changes, until interrupted. The licenses that were found are cached in memory (and on disk), so
+// only the new dependencies are scanned again.
+func watchModule(dir string, args ...string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var last, lastConfig string
+	var previous map[string]bool // issues of the previous check
+	for {
+		if stamp := filesStamp(*configFile); stamp != lastConfig {
+			if lastConfig != "" {
+				reloadConfig()
+			}
+			lastConfig = stamp
+			last = "" // check again with the new policy
+		}
+		if stamp := filesStamp(modFiles(dir)...); stamp != last {
+			last = stamp
+			started = time.Now()
+			mr, err := checkModule(dir, args...)
+			if err != nil {
+				mr = &licenseguard.ModuleReport{Dir: dir, Error: err.Error()}
+			}
+			writeReport(licenseguard.Report{SchemaVersion: licenseguard.SchemaVersion, Modules: []licenseguard.ModuleReport{*mr}}, false)
+			current := map[string]bool{}
+			for _, issue := range mr.Issues {
+				current[issue.String()] = true
+				if previous != nil && !previous[issue.String()] {
+					fmt.Fprintf(os.Stderr, "new: %s\n", issue.String())
+				}
+			}
+			previous = current
+			fmt.Fprintf(os.Stderr, "== %s: %d package(s), %d issue(s), %d warning(s); watching for changes ==\n",
+				time.Now().Format(time.TimeOnly), len(mr.Packages), len(mr.Issues), len(mr.Warnings))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reloadConfig loads the configuration file again after it changed; the previous configuration is kept if it's invalid
+func reloadConfig() {
+	config, err := licenseguard.LoadConfig(*configFile, isFlagSet("config"))
+	if err != nil {
+		logger.Warn("keeping the previous configuration", "err", err)
+		return
+	}
+	if len(allowLicenses) > 0 {
+		config.Allow = allowLicenses
+	}
+	opts.Config = config
+}
+
+// modFiles returns the files in dir that change when dependencies are added, removed or upgraded
+func modFiles(dir string) []string {
+	var files []string
+	for _, name := range []string{"go.mod", "go.sum", "go.work", "go.work.sum"} {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// filesStamp returns a string that changes whenever one of the files changes (or is created or removed)
+func filesStamp(files ...string) string {
+	var stamp string
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			stamp += fmt.Sprintf("%s:%d:%d;", file, fi.Size(), fi.ModTime().UnixNano())
+		} else if file != "" {
+			stamp += file + ":-;"
+		}
+	}
+	return stamp
+}